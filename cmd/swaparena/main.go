@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("swaparena", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	botSpec := flags.String("bot", "expectimax:nodes=2000", "bot under test: simple or expectimax[:depth=N,nodes=N,movetime=D]")
+	opponentSpec := flags.String("opponent", "simple", "baseline bot, in the same form as --bot")
+	games := flags.Int("games", 100, "most games to play")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "games to play at once")
+	fen := flags.String("fen", engine.StartFEN, "start every game from this SwapFEN or FEN position")
+	rules := flags.String("rules", "classic", "rules preset: "+strings.Join(engine.RulesPresetNames(), ", "))
+	seed := flags.Int64("seed", 1, "swap seed of the first game; each later game adds one")
+	maxPlies := flags.Int("max-plies", 400, "draw games that last longer; 0 means no limit")
+	out := flags.String("out", "arena.csv", "file to write each game's result to")
+	elo0 := flags.Float64("elo0", 0, "SPRT: Elo difference of the null hypothesis")
+	elo1 := flags.Float64("elo1", 20, "SPRT: Elo difference of the alternative hypothesis")
+	alpha := flags.Float64("alpha", 0.05, "SPRT: false positive rate")
+	beta := flags.Float64("beta", 0.05, "SPRT: false negative rate")
+	useSPRT := flags.Bool("sprt", true, "stop as soon as the SPRT accepts a hypothesis")
+	quiet := flags.Bool("quiet", false, "only print the summary")
+	flags.Usage = func() {
+		fmt.Fprintf(stdout, "Usage: swaparena [--bot=SPEC] [--opponent=SPEC] [--games=N] [--concurrency=N] [--fen=FEN] [--rules=PRESET] [--seed=N] [--max-plies=N] [--out=FILE] [--elo0=E] [--elo1=E] [--alpha=A] [--beta=B] [--sprt] [--quiet]\n")
+		fmt.Fprintf(stdout, "Plays bot-vs-bot games with alternating colors and a new seed per game, and reports the Elo difference of --bot over --opponent.\n")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *games < 1 {
+		fmt.Fprintf(stderr, "invalid games %d; expected 1 or more\n", *games)
+		return 2
+	}
+	if *concurrency < 1 {
+		fmt.Fprintf(stderr, "invalid concurrency %d; expected 1 or more\n", *concurrency)
+		return 2
+	}
+	if *alpha <= 0 || *alpha >= 1 || *beta <= 0 || *beta >= 1 || *elo0 >= *elo1 {
+		fmt.Fprintf(stderr, "invalid SPRT parameters; expected alpha and beta in (0, 1) and elo0 below elo1\n")
+		return 2
+	}
+	if _, err := engine.ParseFEN(*fen); err != nil {
+		fmt.Fprintf(stderr, "invalid fen %q: %v\n", *fen, err)
+		return 2
+	}
+	preset, err := engine.RulesPreset(*rules)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid rules: %v\n", err)
+		return 2
+	}
+	m := match{fen: *fen, rules: preset, seed: *seed, maxPlies: *maxPlies, botName: *botSpec, opponentName: *opponentSpec}
+	if m.bot, err = parseBot(*botSpec); err != nil {
+		fmt.Fprintf(stderr, "invalid --bot: %v\n", err)
+		return 2
+	}
+	if m.opponent, err = parseBot(*opponentSpec); err != nil {
+		fmt.Fprintf(stderr, "invalid --opponent: %v\n", err)
+		return 2
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot write results: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+	records := csv.NewWriter(file)
+	records.Write([]string{"game", "seed", "white", "black", "result", "outcome", "plies"})
+
+	test := sprt{elo0: *elo0, elo1: *elo1, alpha: *alpha, beta: *beta}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var t tally
+	verdict := sprtContinue
+	var failure error
+	for res := range m.play(ctx, *games, *concurrency) {
+		if failure != nil || verdict != sprtContinue {
+			// stopping: let the games in progress finish
+			continue
+		}
+		if res.err != nil {
+			failure = res.err
+			cancel()
+			continue
+		}
+
+		white, black := m.botName, m.opponentName
+		if !res.botWhite {
+			white, black = black, white
+		}
+		records.Write([]string{strconv.Itoa(res.index + 1), strconv.FormatInt(res.seed, 10), white, black, res.resultString(), res.outcome, strconv.Itoa(res.plies)})
+		switch res.points() {
+		case 1:
+			t.wins++
+		case 0:
+			t.losses++
+		default:
+			t.draws++
+		}
+		if !*quiet {
+			fmt.Fprintf(stdout, "Finished game %d (%s vs %s): %s {%s}\n", res.index+1, white, black, res.resultString(), res.outcome)
+		}
+		if *useSPRT {
+			if verdict = test.verdict(t); verdict != sprtContinue {
+				cancel()
+			}
+		}
+	}
+
+	records.Flush()
+	if err := records.Error(); err != nil {
+		fmt.Fprintf(stderr, "cannot write results: %v\n", err)
+		return 1
+	}
+	if failure != nil {
+		fmt.Fprintf(stderr, "match failed: %v\n", failure)
+		return 1
+	}
+
+	diff, margin := t.elo()
+	verdict = test.verdict(t)
+	lower, upper := test.bounds()
+	fmt.Fprintf(stdout, "Score of %s vs %s: %d - %d - %d [%.3f] %d\n", m.botName, m.opponentName, t.wins, t.losses, t.draws, t.score(), t.games())
+	fmt.Fprintf(stdout, "Elo difference: %s +/- %s (95%%)\n", formatElo(diff), strings.TrimPrefix(formatElo(margin), "+"))
+	fmt.Fprintf(stdout, "SPRT: llr %.2f (%.2f, %.2f), elo0 %g, elo1 %g: %s\n", test.llr(t), lower, upper, test.elo0, test.elo1, verdict)
+	fmt.Fprintf(stdout, "Results written to %s\n", *out)
+	return 0
+}
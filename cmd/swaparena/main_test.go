@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/divijg19/Swapchess/engine/bot"
+)
+
+func TestRunPlaysAMatch(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "results.csv")
+	var stdout, stderr strings.Builder
+	args := []string{"--bot=simple", "--opponent=simple", "--games=4", "--concurrency=2", "--max-plies=12", "--seed=7", "--out=" + out}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+
+	text := stdout.String()
+	for _, want := range []string{
+		"Finished game 1 (simple vs simple): 1/2-1/2 {ply limit}\n",
+		"Score of simple vs simple: 0 - 0 - 4 [0.500] 4\n",
+		"Elo difference: +0.0 +/- ",
+		"SPRT: llr 0.00 (-2.94, 2.94), elo0 0, elo1 20: continue\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in the output:\n%s", want, text)
+		}
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 5 || lines[0] != "game,seed,white,black,result,outcome,plies" {
+		t.Fatalf("unexpected results file:\n%s", data)
+	}
+	for _, line := range lines[1:] {
+		if !strings.HasSuffix(line, ",simple,simple,1/2-1/2,ply limit,12") {
+			t.Fatalf("unexpected result line %q", line)
+		}
+	}
+	if !strings.Contains(string(data), "\n2,8,") {
+		t.Fatalf("expected game 2 to use seed 8:\n%s", data)
+	}
+}
+
+func TestPlayGameAlternatesColors(t *testing.T) {
+	m := match{bot: mustParseBot(t, "simple"), opponent: mustParseBot(t, "expectimax:depth=2"), fen: "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", seed: 3}
+	m.rules.Swaps = 0
+	white := m.playGame(0)
+	if white.err != nil || !white.botWhite || white.points() != 1 || white.outcome != "checkmate" || white.plies != 1 {
+		t.Fatalf("expected the bot to mate at once as white, got %+v", white)
+	}
+	black := m.playGame(1)
+	if black.err != nil || black.botWhite || black.points() != 0 || black.seed != 4 {
+		t.Fatalf("expected the bot to be mated as black, got %+v", black)
+	}
+}
+
+func mustParseBot(t *testing.T, spec string) bot.Bot {
+	t.Helper()
+	b, err := parseBot(spec)
+	if err != nil {
+		t.Fatalf("parseBot(%q) returned error: %v", spec, err)
+	}
+	return b
+}
+
+func TestParseBot(t *testing.T) {
+	for _, spec := range []string{"simple", "expectimax", "expectimax:depth=3,nodes=100,movetime=50ms"} {
+		if _, err := parseBot(spec); err != nil {
+			t.Fatalf("parseBot(%q) returned error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "random", "simple:depth=2", "expectimax:depth=x", "expectimax:width=2"} {
+		if _, err := parseBot(spec); err == nil {
+			t.Fatalf("expected parseBot(%q) to fail", spec)
+		}
+	}
+}
+
+func TestRunRejectsBadInput(t *testing.T) {
+	for _, args := range [][]string{
+		{"--games=0"},
+		{"--concurrency=0"},
+		{"--bot=random"},
+		{"--opponent=expectimax:depth=x"},
+		{"--rules=unknown"},
+		{"--fen=nonsense"},
+		{"--elo0=10", "--elo1=5"},
+	} {
+		var stdout, stderr strings.Builder
+		if code := run(append(args, "--out="+filepath.Join(t.TempDir(), "r.csv")), &stdout, &stderr); code != 2 {
+			t.Fatalf("%v: expected exit code 2, got %d", args, code)
+		}
+		if stderr.Len() == 0 {
+			t.Fatalf("%v: expected an error message", args)
+		}
+	}
+}
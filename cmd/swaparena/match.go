@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/engine/bot"
+)
+
+// parseBot builds a bot from a spec: "simple", or "expectimax" optionally
+// followed by limits, e.g. "expectimax:depth=3" or
+// "expectimax:nodes=5000,movetime=200ms".
+func parseBot(spec string) (bot.Bot, error) {
+	name, params, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch name {
+	case "simple":
+		if params != "" {
+			return nil, fmt.Errorf("bot %q takes no options", name)
+		}
+		return bot.Simple{}, nil
+	case "expectimax":
+		var e bot.Expectimax
+		if params == "" {
+			return e, nil
+		}
+		for _, param := range strings.Split(params, ",") {
+			key, value, _ := strings.Cut(param, "=")
+			var err error
+			switch key {
+			case "depth":
+				e.Depth, err = strconv.Atoi(value)
+			case "nodes":
+				e.MaxNodes, err = strconv.Atoi(value)
+			case "movetime":
+				e.MoveTime, err = time.ParseDuration(value)
+			default:
+				return nil, fmt.Errorf("unknown expectimax option %q; expected depth, nodes or movetime", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid expectimax %s %q", key, value)
+			}
+		}
+		return e, nil
+	default:
+		return nil, fmt.Errorf("unknown bot %q; expected simple or expectimax", name)
+	}
+}
+
+// match describes the games to play.
+type match struct {
+	// bot is under test and opponent is the baseline.
+	bot, opponent         bot.Bot
+	botName, opponentName string
+	fen                   string
+	rules                 engine.Rules
+	seed                  int64
+	maxPlies              int
+}
+
+// gameResult is the outcome of one game.
+type gameResult struct {
+	index int
+	seed  int64
+	// botWhite tells which side the bot under test played.
+	botWhite bool
+	// winner is set unless the game was drawn.
+	winner  *engine.Color
+	outcome string
+	plies   int
+	err     error
+}
+
+// points is the score of the bot under test: 1, 0.5 or 0.
+func (r gameResult) points() float64 {
+	switch {
+	case r.winner == nil:
+		return 0.5
+	case (*r.winner == engine.White) == r.botWhite:
+		return 1
+	}
+	return 0
+}
+
+// resultString is the game result in PGN style, e.g. "1-0".
+func (r gameResult) resultString() string {
+	switch {
+	case r.winner == nil:
+		return "1/2-1/2"
+	case *r.winner == engine.White:
+		return "1-0"
+	}
+	return "0-1"
+}
+
+// play runs games concurrent games at a time and sends each result as it
+// finishes. Game i uses seed m.seed+i, and the bot under test plays white in
+// the even games. Games not yet started when ctx is done are skipped.
+func (m match) play(ctx context.Context, games, concurrency int) <-chan gameResult {
+	indexes := make(chan int)
+	results := make(chan gameResult)
+	go func() {
+		defer close(indexes)
+		for i := 0; i < games; i++ {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results <- m.playGame(i)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// playGame plays game i to its end with engine.ApplyMove, judging it with
+// engine.GameOutcome. Games longer than maxPlies are drawn.
+func (m match) playGame(i int) gameResult {
+	res := gameResult{index: i, seed: m.seed + int64(i), botWhite: i%2 == 0}
+	state, err := engine.ParseFEN(m.fen)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	rules := m.rules
+	state.Rules = &rules
+	state.RandSeed = res.seed
+
+	players := [2]bot.Bot{m.bot, m.opponent}
+	if !res.botWhite {
+		players = [2]bot.Bot{m.opponent, m.bot}
+	}
+	for {
+		switch outcome := engine.GameOutcome(state); {
+		case outcome == engine.OutcomeCheckmate || outcome == engine.OutcomeKingCaptured:
+			winner := 1 - state.Turn
+			res.winner = &winner
+			res.outcome = outcome.String()
+			return res
+		case outcome.IsDraw():
+			res.outcome = outcome.String()
+			return res
+		}
+		if m.maxPlies > 0 && res.plies >= m.maxPlies {
+			res.outcome = "ply limit"
+			return res
+		}
+
+		move, err := players[state.Turn].ChooseMove(state)
+		if err == nil {
+			err = engine.ApplyMove(state, move)
+		}
+		if err != nil {
+			res.err = fmt.Errorf("game %d, ply %d: %s: %w", i+1, res.plies+1, state.Turn, err)
+			return res
+		}
+		res.plies++
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// tally counts a match's results from the point of view of the bot under test.
+type tally struct {
+	wins, draws, losses int
+}
+
+func (t tally) games() int {
+	return t.wins + t.draws + t.losses
+}
+
+// score is the mean points per game, counting a draw as half a point.
+func (t tally) score() float64 {
+	return (float64(t.wins) + float64(t.draws)/2) / float64(t.games())
+}
+
+// variance is the per-game variance of the points scored.
+func (t tally) variance() float64 {
+	n, s := float64(t.games()), t.score()
+	return (float64(t.wins)*(1-s)*(1-s) + float64(t.draws)*(0.5-s)*(0.5-s) + float64(t.losses)*s*s) / n
+}
+
+// elo returns the Elo difference the score suggests and the half-width of
+// its 95% confidence interval. Both are infinite for a perfect or a zero
+// score, and the margin is NaN before there are two games.
+func (t tally) elo() (diff, margin float64) {
+	n := float64(t.games())
+	if n == 0 {
+		return 0, math.NaN()
+	}
+	s := t.score()
+	diff = scoreToElo(s)
+	if n < 2 {
+		return diff, math.NaN()
+	}
+	se := math.Sqrt(t.variance() / n)
+	lo, hi := scoreToElo(s-1.96*se), scoreToElo(s+1.96*se)
+	return diff, (hi - lo) / 2
+}
+
+// scoreToElo converts an expected score into an Elo difference with the
+// logistic model; eloToScore is its inverse.
+func scoreToElo(s float64) float64 {
+	switch {
+	case s <= 0:
+		return math.Inf(-1)
+	case s >= 1:
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/s-1)
+}
+
+func eloToScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// sprt is a sequential probability ratio test of the hypotheses that the
+// bot under test is elo0 (H0) or elo1 (H1) Elo stronger, with error rates
+// alpha and beta.
+type sprt struct {
+	elo0, elo1  float64
+	alpha, beta float64
+}
+
+// verdicts of an sprt
+const (
+	sprtContinue = "continue"
+	sprtH0       = "H0 accepted"
+	sprtH1       = "H1 accepted"
+)
+
+// bounds returns the log-likelihood ratios at which the test stops.
+func (p sprt) bounds() (lower, upper float64) {
+	return math.Log(p.beta / (1 - p.alpha)), math.Log((1 - p.beta) / p.alpha)
+}
+
+// llr returns the log-likelihood ratio of H1 against H0 for t, using the
+// normal approximation of the per-game score. It is 0 while every game has
+// ended alike, since the variance is still unknown.
+func (p sprt) llr(t tally) float64 {
+	variance := t.variance()
+	if t.games() == 0 || variance == 0 {
+		return 0
+	}
+	s0, s1 := eloToScore(p.elo0), eloToScore(p.elo1)
+	return float64(t.games()) * (s1 - s0) * (2*t.score() - s0 - s1) / (2 * variance)
+}
+
+// verdict tells whether the test can stop after t.
+func (p sprt) verdict(t tally) string {
+	llr := p.llr(t)
+	lower, upper := p.bounds()
+	switch {
+	case llr >= upper:
+		return sprtH1
+	case llr <= lower:
+		return sprtH0
+	}
+	return sprtContinue
+}
+
+// formatElo prints an Elo value with its sign, e.g. "+12.3" or "-inf".
+func formatElo(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "nan"
+	case math.IsInf(v, 1):
+		return "+inf"
+	case math.IsInf(v, -1):
+		return "-inf"
+	case v == 0:
+		// no minus sign for negative zero
+		return "+0.0"
+	}
+	return fmt.Sprintf("%+.1f", v)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTallyElo(t *testing.T) {
+	even := tally{wins: 30, draws: 40, losses: 30}
+	if diff, margin := even.elo(); diff != 0 || margin <= 0 || math.IsInf(margin, 0) {
+		t.Fatalf("expected an even score to give 0 with a finite margin, got %v +/- %v", diff, margin)
+	}
+
+	// a 75% score is about 191 Elo
+	strong := tally{wins: 60, draws: 30, losses: 10}
+	diff, margin := strong.elo()
+	if math.Abs(diff-190.8) > 0.1 {
+		t.Fatalf("expected about +190.8 Elo, got %v", diff)
+	}
+	if _, wider := (tally{wins: 6, draws: 3, losses: 1}).elo(); wider <= margin {
+		t.Fatalf("expected fewer games to widen the margin, got %v against %v", wider, margin)
+	}
+
+	if diff, _ := (tally{wins: 3}).elo(); !math.IsInf(diff, 1) {
+		t.Fatalf("expected a perfect score to be +inf, got %v", diff)
+	}
+}
+
+func TestSPRTVerdicts(t *testing.T) {
+	test := sprt{elo0: 0, elo1: 20, alpha: 0.05, beta: 0.05}
+	lower, upper := test.bounds()
+	if math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Fatalf("unexpected bounds (%v, %v)", lower, upper)
+	}
+
+	for _, tc := range []struct {
+		t    tally
+		want string
+	}{
+		{tally{wins: 20, draws: 10, losses: 15}, sprtContinue},
+		{tally{wins: 600, draws: 200, losses: 400}, sprtH1},
+		{tally{wins: 400, draws: 200, losses: 600}, sprtH0},
+		{tally{wins: 5}, sprtContinue},
+	} {
+		if got := test.verdict(tc.t); got != tc.want {
+			t.Fatalf("%+v: expected %q, got %q (llr %v)", tc.t, tc.want, got, test.llr(tc.t))
+		}
+	}
+}
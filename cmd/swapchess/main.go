@@ -5,14 +5,22 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"os"
+	"strings"
 
+	"github.com/divijg19/Swapchess/engine"
 	"github.com/divijg19/Swapchess/internal/app"
 	cliui "github.com/divijg19/Swapchess/internal/ui/cli"
 	tuiui "github.com/divijg19/Swapchess/internal/ui/tui"
 )
 
-type runFunc func(string) error
+type runFunc func(app.Options) error
+
+// randomSeed picks the swap seed for games started without --seed.
+var randomSeed = func() int64 {
+	return int64(rand.Uint32())
+}
 
 func main() {
 	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, cliui.Run, tuiui.Run))
@@ -26,8 +34,13 @@ func run(args []string, stdout, stderr io.Writer, cliRunner, tuiRunner runFunc)
 	mode := flags.String("mode", string(app.ModeTUI), "run mode: tui or cli")
 	showVersion := flags.Bool("version", false, "print version and exit")
 	debugRenderer := flags.String("debug-renderer", "", "")
+	fen := flags.String("fen", "", "start from a SwapFEN or FEN position")
+	seed := flags.Int64("seed", 0, "swap seed; random when omitted")
+	rules := flags.String("rules", "classic", "rules preset: "+strings.Join(engine.RulesPresetNames(), ", "))
+	white := flags.String("white", string(app.PlayerHuman), "who plays white: human, bot or exec:COMMAND for an external engine")
+	black := flags.String("black", string(app.PlayerHuman), "who plays black: human, bot or exec:COMMAND for an external engine")
 	flags.Usage = func() {
-		fmt.Fprintf(stdout, "Usage: swapchess [--cli] [--mode=tui|cli] [--version]\n")
+		fmt.Fprintf(stdout, "Usage: swapchess [--cli] [--mode=tui|cli] [--fen=FEN] [--seed=N] [--rules=PRESET] [--white=human|bot|exec:CMD] [--black=human|bot|exec:CMD] [--version]\n")
 		fmt.Fprintf(stdout, "Default mode is the alt-screen terminal UI.\n")
 	}
 
@@ -58,12 +71,56 @@ func run(args []string, stdout, stderr io.Writer, cliRunner, tuiRunner runFunc)
 		return 2
 	}
 
-	var err error
+	if *fen != "" {
+		if _, err := engine.ParseFEN(*fen); err != nil {
+			fmt.Fprintf(stderr, "invalid fen %q: %v\n", *fen, err)
+			return 2
+		}
+	}
+
+	if _, err := engine.RulesPreset(*rules); err != nil {
+		fmt.Fprintf(stderr, "invalid rules: %v\n", err)
+		return 2
+	}
+
+	whitePlayer, err := app.ParsePlayer(*white)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid --white: %v\n", err)
+		return 2
+	}
+	blackPlayer, err := app.ParsePlayer(*black)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid --black: %v\n", err)
+		return 2
+	}
+
+	seedSet := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seedSet = true
+		}
+	})
+	// A full SwapFEN carries its own seed; keep it unless --seed overrides it.
+	if !seedSet && len(strings.Fields(*fen)) < 8 {
+		*seed = randomSeed()
+		seedSet = true
+	}
+
+	opts := app.Options{
+		DebugRenderer: *debugRenderer,
+		FEN:           *fen,
+		Seed:          *seed,
+		SeedSet:       seedSet,
+		Rules:         *rules,
+		White:         whitePlayer,
+		Black:         blackPlayer,
+	}
+
 	switch app.Mode(resolvedMode) {
 	case app.ModeCLI:
-		err = cliRunner(*debugRenderer)
+		err = cliRunner(opts)
 	default:
-		err = tuiRunner(*debugRenderer)
+		err = tuiRunner(opts)
 	}
 
 	if err != nil {
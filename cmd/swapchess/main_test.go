@@ -14,14 +14,14 @@ func TestRunDefaultsToTUI(t *testing.T) {
 	debugRenderer := ""
 
 	exitCode := run(nil, &stdout, &stderr,
-		func(debug string) error {
+		func(opts app.Options) error {
 			called = "cli"
-			debugRenderer = debug
+			debugRenderer = opts.DebugRenderer
 			return nil
 		},
-		func(debug string) error {
+		func(opts app.Options) error {
 			called = "tui"
-			debugRenderer = debug
+			debugRenderer = opts.DebugRenderer
 			return nil
 		},
 	)
@@ -42,12 +42,12 @@ func TestRunCLIFlagOverridesMode(t *testing.T) {
 	called := ""
 
 	exitCode := run([]string{"--mode=tui", "--cli", "--debug-renderer=engine"}, &stdout, &stderr,
-		func(debug string) error {
-			called = "cli:" + debug
+		func(opts app.Options) error {
+			called = "cli:" + opts.DebugRenderer
 			return nil
 		},
-		func(debug string) error {
-			called = "tui:" + debug
+		func(opts app.Options) error {
+			called = "tui:" + opts.DebugRenderer
 			return nil
 		},
 	)
@@ -65,11 +65,11 @@ func TestRunModeCLIUsesCLIRunner(t *testing.T) {
 	called := ""
 
 	exitCode := run([]string{"--mode=cli"}, &stdout, &stderr,
-		func(debug string) error {
+		func(app.Options) error {
 			called = "cli"
 			return nil
 		},
-		func(debug string) error {
+		func(app.Options) error {
 			called = "tui"
 			return nil
 		},
@@ -87,8 +87,8 @@ func TestRunRejectsInvalidMode(t *testing.T) {
 	var stdout, stderr strings.Builder
 
 	exitCode := run([]string{"--mode=bad"}, &stdout, &stderr,
-		func(string) error { return nil },
-		func(string) error { return nil },
+		func(app.Options) error { return nil },
+		func(app.Options) error { return nil },
 	)
 
 	if exitCode != 2 {
@@ -103,8 +103,8 @@ func TestRunRejectsInvalidDebugRenderer(t *testing.T) {
 	var stdout, stderr strings.Builder
 
 	exitCode := run([]string{"--debug-renderer=bad"}, &stdout, &stderr,
-		func(string) error { return nil },
-		func(string) error { return nil },
+		func(app.Options) error { return nil },
+		func(app.Options) error { return nil },
 	)
 
 	if exitCode != 2 {
@@ -119,8 +119,8 @@ func TestRunReturnsOneWhenRunnerFails(t *testing.T) {
 	var stdout, stderr strings.Builder
 
 	exitCode := run([]string{"--cli"}, &stdout, &stderr,
-		func(string) error { return errors.New("boom") },
-		func(string) error { return nil },
+		func(app.Options) error { return errors.New("boom") },
+		func(app.Options) error { return nil },
 	)
 
 	if exitCode != 1 {
@@ -135,14 +135,14 @@ func TestRunHelpWritesUsage(t *testing.T) {
 	var stdout, stderr strings.Builder
 
 	exitCode := run([]string{"--help"}, &stdout, &stderr,
-		func(string) error { return nil },
-		func(string) error { return nil },
+		func(app.Options) error { return nil },
+		func(app.Options) error { return nil },
 	)
 
 	if exitCode != 0 {
 		t.Fatalf("expected zero exit code for help, got %d", exitCode)
 	}
-	if !strings.Contains(stdout.String(), "Usage: swapchess [--cli] [--mode=tui|cli] [--version]") {
+	if !strings.Contains(stdout.String(), "Usage: swapchess [--cli] [--mode=tui|cli] [--fen=FEN] [--seed=N] [--rules=PRESET] [--white=human|bot|exec:CMD] [--black=human|bot|exec:CMD] [--version]") {
 		t.Fatalf("expected usage in stdout, got %q", stdout.String())
 	}
 }
@@ -151,8 +151,8 @@ func TestRunVersionWritesVersion(t *testing.T) {
 	var stdout, stderr strings.Builder
 
 	exitCode := run([]string{"--version"}, &stdout, &stderr,
-		func(string) error { return nil },
-		func(string) error { return nil },
+		func(app.Options) error { return nil },
+		func(app.Options) error { return nil },
 	)
 
 	if exitCode != 0 {
@@ -165,3 +165,181 @@ func TestRunVersionWritesVersion(t *testing.T) {
 		t.Fatalf("expected empty stderr for version, got %q", stderr.String())
 	}
 }
+
+func TestRunPassesFENToRunner(t *testing.T) {
+	var stdout, stderr strings.Builder
+	fen := "4k3/8/8/8/8/8/8/4K3 w - - 0 1 s 7"
+	got := ""
+
+	exitCode := run([]string{"--fen=" + fen}, &stdout, &stderr,
+		func(app.Options) error { return nil },
+		func(opts app.Options) error {
+			got = opts.FEN
+			return nil
+		},
+	)
+
+	if exitCode != 0 {
+		t.Fatalf("expected zero exit code, got %d", exitCode)
+	}
+	if got != fen {
+		t.Fatalf("expected FEN %q to reach the runner, got %q", fen, got)
+	}
+}
+
+func TestRunRejectsInvalidFEN(t *testing.T) {
+	var stdout, stderr strings.Builder
+
+	exitCode := run([]string{"--fen=bad"}, &stdout, &stderr,
+		func(app.Options) error { return nil },
+		func(app.Options) error { return nil },
+	)
+
+	if exitCode != 2 {
+		t.Fatalf("expected exit code 2, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), `invalid fen "bad"`) {
+		t.Fatalf("expected invalid fen error, got %q", stderr.String())
+	}
+}
+
+func TestRunPassesSeedToRunner(t *testing.T) {
+	var stdout, stderr strings.Builder
+	var got app.Options
+
+	exitCode := run([]string{"--seed=-12"}, &stdout, &stderr,
+		func(app.Options) error { return nil },
+		func(opts app.Options) error {
+			got = opts
+			return nil
+		},
+	)
+
+	if exitCode != 0 {
+		t.Fatalf("expected zero exit code, got %d", exitCode)
+	}
+	if !got.SeedSet || got.Seed != -12 {
+		t.Fatalf("expected seed -12 to reach the runner, got %+v", got)
+	}
+}
+
+func TestRunDefaultsToRandomSeed(t *testing.T) {
+	original := randomSeed
+	randomSeed = func() int64 { return 4242 }
+	t.Cleanup(func() { randomSeed = original })
+
+	var stdout, stderr strings.Builder
+	var got app.Options
+	capture := func(opts app.Options) error {
+		got = opts
+		return nil
+	}
+
+	if exitCode := run(nil, &stdout, &stderr, capture, capture); exitCode != 0 {
+		t.Fatalf("expected zero exit code, got %d", exitCode)
+	}
+	if !got.SeedSet || got.Seed != 4242 {
+		t.Fatalf("expected random seed 4242, got %+v", got)
+	}
+
+	// A full SwapFEN already names its seed.
+	fen := "4k3/8/8/8/8/8/8/4K3 w - - 0 1 - 7"
+	if exitCode := run([]string{"--fen=" + fen}, &stdout, &stderr, capture, capture); exitCode != 0 {
+		t.Fatalf("expected zero exit code, got %d", exitCode)
+	}
+	if got.SeedSet {
+		t.Fatalf("expected the SwapFEN seed to be kept, got %+v", got)
+	}
+}
+
+func TestRunPassesRulesPresetToRunner(t *testing.T) {
+	var stdout, stderr strings.Builder
+	got := ""
+
+	exitCode := run([]string{"--rules=gentle"}, &stdout, &stderr,
+		func(app.Options) error { return nil },
+		func(opts app.Options) error {
+			got = opts.Rules
+			return nil
+		},
+	)
+
+	if exitCode != 0 {
+		t.Fatalf("expected zero exit code, got %d", exitCode)
+	}
+	if got != "gentle" {
+		t.Fatalf("expected rules preset to reach the runner, got %q", got)
+	}
+}
+
+func TestRunRejectsUnknownRulesPreset(t *testing.T) {
+	var stdout, stderr strings.Builder
+
+	exitCode := run([]string{"--rules=chaos"}, &stdout, &stderr,
+		func(app.Options) error { return nil },
+		func(app.Options) error { return nil },
+	)
+
+	if exitCode != 2 {
+		t.Fatalf("expected exit code 2, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), `unknown rules preset "chaos"`) || !strings.Contains(stderr.String(), "classic") {
+		t.Fatalf("expected unknown preset error listing presets, got %q", stderr.String())
+	}
+}
+
+func TestRunPassesPlayersToRunner(t *testing.T) {
+	var stdout, stderr strings.Builder
+	var got app.Options
+
+	exitCode := run([]string{"--cli", "--black=bot"}, &stdout, &stderr,
+		func(opts app.Options) error {
+			got = opts
+			return nil
+		},
+		func(app.Options) error { return nil },
+	)
+
+	if exitCode != 0 {
+		t.Fatalf("expected zero exit code, got %d", exitCode)
+	}
+	if got.White != app.PlayerHuman || got.Black != app.PlayerBot {
+		t.Fatalf("expected human white and bot black, got %q and %q", got.White, got.Black)
+	}
+}
+
+func TestRunPassesExecPlayersToRunner(t *testing.T) {
+	var stdout, stderr strings.Builder
+	var got app.Options
+
+	exitCode := run([]string{"--white=exec:./My Engine --fast"}, &stdout, &stderr,
+		func(app.Options) error { return nil },
+		func(opts app.Options) error {
+			got = opts
+			return nil
+		},
+	)
+
+	if exitCode != 0 {
+		t.Fatalf("expected zero exit code, got %d", exitCode)
+	}
+	if command, ok := got.White.ExecCommand(); !ok || command != "./My Engine --fast" {
+		t.Fatalf("expected the engine command to be kept, got %q", got.White)
+	}
+}
+
+func TestRunRejectsUnknownPlayer(t *testing.T) {
+	var stdout, stderr strings.Builder
+
+	exitCode := run([]string{"--white=robot"}, &stdout, &stderr,
+		func(app.Options) error { return nil },
+		func(app.Options) error { return nil },
+	)
+
+	if exitCode != 2 {
+		t.Fatalf("expected exit code 2, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), `unknown player "robot"`) {
+		t.Fatalf("expected unknown player error, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/divijg19/Swapchess/internal/app"
+	"github.com/divijg19/Swapchess/internal/cecp"
+	"github.com/divijg19/Swapchess/internal/uci"
+)
+
+const (
+	protocolAuto   = "auto"
+	protocolUCI    = "uci"
+	protocolXBoard = "xboard"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("swapengine", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	protocol := flags.String("protocol", protocolAuto, "protocol to speak: uci, xboard, or auto to tell from the first command")
+	flags.Usage = func() {
+		fmt.Fprintf(stdout, "Usage: swapengine [--protocol=auto|uci|xboard]\n")
+		fmt.Fprintf(stdout, "Speaks the UCI-style Swapchess engine protocol, or CECP for XBoard GUIs, on stdin and stdout.\n")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments %q\n", flags.Args())
+		return 2
+	}
+	if *protocol != protocolAuto && *protocol != protocolUCI && *protocol != protocolXBoard {
+		fmt.Fprintf(stderr, "invalid protocol %q; expected auto, uci or xboard\n", *protocol)
+		return 2
+	}
+
+	in := bufio.NewReader(stdin)
+	if *protocol == protocolAuto {
+		// XBoard GUIs open with the xboard command
+		first, err := in.ReadString('\n')
+		for err == nil && strings.TrimSpace(first) == "" {
+			first, err = in.ReadString('\n')
+		}
+		if fields := strings.Fields(first); len(fields) > 0 && fields[0] == "xboard" {
+			*protocol = protocolXBoard
+		}
+		stdin = io.MultiReader(strings.NewReader(first), in)
+	}
+
+	name := "Swapchess " + app.Version
+	var err error
+	if *protocol == protocolXBoard {
+		adapter := cecp.NewAdapter(stdout)
+		adapter.Name = name
+		err = adapter.Serve(stdin)
+	} else {
+		server := uci.NewServer(stdout)
+		server.Name = name
+		err = server.Serve(stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot read commands: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunServesTheProtocol(t *testing.T) {
+	var stdout, stderr strings.Builder
+	stdin := strings.NewReader("uci\nposition startpos\nfen\nquit\nisready\n")
+	if code := run(nil, stdin, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	text := stdout.String()
+	for _, want := range []string{"id name Swapchess v", "uciok\n", "fen rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 - 1\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in the output:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "readyok") {
+		t.Fatalf("expected quit to end the session:\n%s", text)
+	}
+}
+
+func TestRunRejectsArguments(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run([]string{"extra"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit code 2, got %d", code)
+	}
+}
+
+func TestRunDetectsXBoard(t *testing.T) {
+	var stdout, stderr strings.Builder
+	stdin := strings.NewReader("\nxboard\nprotover 2\nping 1\nquit\n")
+	if code := run(nil, stdin, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	text := stdout.String()
+	for _, want := range []string{`myname="Swapchess v`, `variants="swapchess"`, "pong 1\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in the output:\n%s", want, text)
+		}
+	}
+}
+
+func TestRunSelectsTheProtocol(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run([]string{"--protocol=xboard"}, strings.NewReader("ping 1\nuci\n"), &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	if want := "pong 1\nError (unknown command): uci\n"; stdout.String() != want {
+		t.Fatalf("expected %q, got %q", want, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"--protocol=cecp"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), `invalid protocol "cecp"`) {
+		t.Fatalf("expected the protocol to be rejected, got %q", stderr.String())
+	}
+}
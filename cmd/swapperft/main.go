@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/internal/app"
+)
+
+const (
+	modeChess = "chess"
+	modeSwap  = "swap"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("swapperft", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	fen := flags.String("fen", engine.StartFEN, "start from a SwapFEN or FEN position")
+	depth := flags.Int("depth", 3, "plies to count")
+	mode := flags.String("mode", modeChess, "chess counts plain chess moves; swap also branches on every swap outcome")
+	rules := flags.String("rules", "classic", "rules preset for swap mode: "+strings.Join(engine.RulesPresetNames(), ", "))
+	divide := flags.Bool("divide", false, "print the node count below each root move")
+	flags.Usage = func() {
+		fmt.Fprintf(stdout, "Usage: swapperft [--fen=FEN] [--depth=N] [--mode=chess|swap] [--rules=PRESET] [--divide]\n")
+		fmt.Fprintf(stdout, "Counts the leaf nodes of the move tree. Chess mode disables swaps so counts match standard perft.\n")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *depth < 1 {
+		fmt.Fprintf(stderr, "invalid depth %d; expected 1 or more\n", *depth)
+		return 2
+	}
+
+	state, err := engine.ParseFEN(*fen)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid fen %q: %v\n", *fen, err)
+		return 2
+	}
+
+	preset, err := engine.RulesPreset(*rules)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid rules: %v\n", err)
+		return 2
+	}
+	switch *mode {
+	case modeChess:
+		preset.Swaps = 0
+	case modeSwap:
+	default:
+		fmt.Fprintf(stderr, "invalid mode %q; expected chess or swap\n", *mode)
+		return 2
+	}
+	state.Rules = &preset
+
+	if !*divide {
+		fmt.Fprintf(stdout, "Nodes searched: %d\n", engine.Perft(state, *depth))
+		return 0
+	}
+
+	var total uint64
+	for _, entry := range engine.PerftDivide(state, *depth) {
+		fmt.Fprintf(stdout, "%s: %d\n", entryLabel(entry), entry.Nodes)
+		total += entry.Nodes
+	}
+	fmt.Fprintf(stdout, "\nNodes searched: %d\n", total)
+	return 0
+}
+
+// entryLabel names a divide entry by its move and, in swap mode, its swaps,
+// e.g. "e2e4" or "e2e4 e4<>d1".
+func entryLabel(entry engine.PerftEntry) string {
+	label := app.MoveString(entry.Move)
+	from := entry.Move.To
+	for _, target := range entry.Swaps {
+		label += " " + app.PositionString(from) + "<>" + app.PositionString(target)
+		from = target
+	}
+	return label
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunCountsChessNodes(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run([]string{"--depth=3"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	if got := stdout.String(); got != "Nodes searched: 8902\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
+
+func TestRunDivide(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run([]string{"--depth=2", "--divide"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "e2e4: 20\n") || !strings.HasSuffix(out, "\nNodes searched: 400\n") {
+		t.Fatalf("unexpected divide output:\n%s", out)
+	}
+}
+
+func TestRunSwapModeLabelsSwaps(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run([]string{"--mode=swap", "--depth=1", "--divide"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "e2e4 e4<>e1: 1\n") || !strings.HasSuffix(out, "\nNodes searched: 300\n") {
+		t.Fatalf("unexpected swap divide output:\n%s", out)
+	}
+}
+
+func TestRunRejectsBadInput(t *testing.T) {
+	for _, args := range [][]string{
+		{"--depth=0"},
+		{"--mode=bughouse"},
+		{"--fen=nonsense"},
+		{"--rules=unknown"},
+	} {
+		var stdout, stderr strings.Builder
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Fatalf("%v: expected exit code 2, got %d", args, code)
+		}
+		if stderr.Len() == 0 {
+			t.Fatalf("%v: expected an error message", args)
+		}
+	}
+}
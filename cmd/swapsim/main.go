@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("swapsim", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	games := flags.Int("games", 10000, "playouts to run")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "playouts to run at once")
+	policy := flags.String("policy", policyRandom, "move choice: random, or capture to prefer captures")
+	fen := flags.String("fen", engine.StartFEN, "start every playout from this SwapFEN or FEN position")
+	rules := flags.String("rules", "classic", "rules preset: "+strings.Join(engine.RulesPresetNames(), ", "))
+	seed := flags.Int64("seed", 1, "seed of the first playout; each later playout adds one")
+	maxPlies := flags.Int("max-plies", 400, "draw playouts that last longer; 0 means no limit")
+	format := flags.String("format", "json", "output format: json or csv")
+	out := flags.String("out", "", "file to write the statistics to instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(stdout, "Usage: swapsim [--games=N] [--concurrency=N] [--policy=random|capture] [--fen=FEN] [--rules=PRESET] [--seed=N] [--max-plies=N] [--format=json|csv] [--out=FILE]\n")
+		fmt.Fprintf(stdout, "Plays seeded random games and reports how often the swap rule fires, is suppressed, exposes a king or strands a pawn.\n")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *games < 1 {
+		fmt.Fprintf(stderr, "invalid games %d; expected 1 or more\n", *games)
+		return 2
+	}
+	if *concurrency < 1 {
+		fmt.Fprintf(stderr, "invalid concurrency %d; expected 1 or more\n", *concurrency)
+		return 2
+	}
+	if *policy != policyRandom && *policy != policyCapture {
+		fmt.Fprintf(stderr, "invalid policy %q; expected random or capture\n", *policy)
+		return 2
+	}
+	if *format != "json" && *format != "csv" {
+		fmt.Fprintf(stderr, "invalid format %q; expected json or csv\n", *format)
+		return 2
+	}
+	if _, err := engine.ParseFEN(*fen); err != nil {
+		fmt.Fprintf(stderr, "invalid fen %q: %v\n", *fen, err)
+		return 2
+	}
+	preset, err := engine.RulesPreset(*rules)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid rules: %v\n", err)
+		return 2
+	}
+
+	sim := simulation{fen: *fen, rules: preset, policy: *policy, seed: *seed, maxPlies: *maxPlies}
+	s, err := sim.run(*games, *concurrency)
+	if err != nil {
+		fmt.Fprintf(stderr, "simulation failed: %v\n", err)
+		return 1
+	}
+
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(stderr, "cannot write statistics: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+	if *format == "csv" {
+		err = writeCSV(w, s)
+	} else {
+		err = writeJSON(w, s)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot write statistics: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func writeJSON(w io.Writer, s *stats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// writeCSV writes one metric,value row per statistic, with one row per
+// outcome and suppression reason, e.g. "outcomes.checkmate".
+func writeCSV(w io.Writer, s *stats) error {
+	records := csv.NewWriter(w)
+	records.Write([]string{"metric", "value"})
+	ints := func(name string, v int) { records.Write([]string{name, strconv.Itoa(v)}) }
+	floats := func(name string, v float64) {
+		records.Write([]string{name, strconv.FormatFloat(v, 'f', -1, 64)})
+	}
+	counts := func(prefix string, m map[string]int) {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			ints(prefix+"."+k, m[k])
+		}
+	}
+
+	ints("games", s.Games)
+	ints("plies", s.Plies)
+	floats("mean_length", s.MeanLength)
+	ints("max_length", s.MaxLength)
+	ints("white_wins", s.WhiteWins)
+	ints("black_wins", s.BlackWins)
+	ints("draws", s.Draws)
+	floats("white_score", s.WhiteScore)
+	counts("outcomes", s.Outcomes)
+	ints("swapped_moves", s.SwappedMoves)
+	ints("swaps", s.Swaps)
+	floats("swap_rate", s.SwapRate)
+	counts("suppressed", s.Suppressed)
+	floats("suppression_rate", s.SuppressionRate)
+	ints("king_exposures", s.KingExposures)
+	floats("king_exposure_rate", s.KingExposureRate)
+	ints("back_rank_pawns", s.BackRankPawns)
+	floats("back_rank_pawn_rate", s.BackRankPawnRate)
+	ints("swap_promotions", s.SwapPromotions)
+	records.Flush()
+	return records.Error()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+func TestRunWritesJSON(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run([]string{"--games=20", "--concurrency=3", "--seed=5"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	var s stats
+	if err := json.Unmarshal([]byte(stdout.String()), &s); err != nil {
+		t.Fatalf("expected JSON output, got %v:\n%s", err, stdout.String())
+	}
+	if s.Games != 20 || s.WhiteWins+s.BlackWins+s.Draws != 20 || s.Plies == 0 || s.SwappedMoves == 0 {
+		t.Fatalf("unexpected statistics %+v", s)
+	}
+}
+
+func TestRunWritesCSV(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "stats.csv")
+	var stdout, stderr strings.Builder
+	args := []string{"--games=4", "--max-plies=10", "--format=csv", "--out=" + out}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected zero exit code, got %d (%s)", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("expected nothing on stdout, got %q", stdout.String())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, want := range []string{"metric,value\n", "\ngames,4\n", "\nplies,40\n", "\nmean_length,10\n", "\noutcomes.ply limit,4\n", "\nwhite_score,0.5\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in the CSV:\n%s", want, text)
+		}
+	}
+}
+
+func TestRunRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--games=0"},
+		{"--concurrency=0"},
+		{"--policy=greedy"},
+		{"--format=xml"},
+		{"--rules=wild"},
+		{"--fen=nonsense"},
+	} {
+		var stdout, stderr strings.Builder
+		if code := run(args, &stdout, &stderr); code != 2 || stderr.Len() == 0 {
+			t.Fatalf("%v: expected exit code 2 with an error, got %d (%q)", args, code, stderr.String())
+		}
+	}
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	for _, policy := range []string{policyRandom, policyCapture} {
+		sim := simulation{fen: engine.StartFEN, rules: engine.ClassicRules(), policy: policy, seed: 11, maxPlies: 200}
+		serial, err := sim.run(12, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parallel, err := sim.run(12, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(serial, parallel) {
+			t.Fatalf("%s: expected the same statistics on any number of workers:\n%+v\n%+v", policy, serial, parallel)
+		}
+		sim.seed++
+		other, err := sim.run(12, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(serial, other) {
+			t.Fatalf("%s: expected another seed to play other games", policy)
+		}
+	}
+}
+
+func TestSimulationFollowsTheRules(t *testing.T) {
+	run := func(preset string) *stats {
+		t.Helper()
+		rules, err := engine.RulesPreset(preset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := simulation{fen: engine.StartFEN, rules: rules, policy: policyRandom, seed: 1, maxPlies: 200}.run(20, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	classic := run("classic")
+	if classic.KingExposures == 0 || classic.BackRankPawns == 0 || classic.Swaps != classic.SwappedMoves {
+		t.Fatalf("expected classic swaps to expose kings and strand pawns one swap at a time, got %+v", classic)
+	}
+	if safe := run("safe"); safe.KingExposures != 0 || safe.BackRankPawns != 0 || safe.SwappedMoves == 0 {
+		t.Fatalf("expected safe swaps to never expose a king or strand a pawn, got %+v", safe)
+	}
+	if double := run("double"); double.Swaps <= double.SwappedMoves {
+		t.Fatalf("expected double swaps to count more swaps than swapped moves, got %+v", double)
+	}
+	if relentless := run("relentless"); relentless.Suppressed["move gave check"] != 0 || relentless.Suppressed["reply to check"] != 0 {
+		t.Fatalf("expected relentless swaps to ignore checks, got %+v", relentless.Suppressed)
+	}
+}
+
+func TestCountMoveCountsBackRankPawns(t *testing.T) {
+	// a knight on b1 swapping with the pawn on h2 carries the pawn to b1
+	s := newStats()
+	res := engine.MoveResult{
+		Mover:       engine.White,
+		Piece:       engine.Piece{Kind: engine.Knight, Color: engine.White},
+		Swapped:     true,
+		SwapA:       engine.Position{File: 1, Rank: 0},
+		SwapB:       engine.Position{File: 7, Rank: 1},
+		SwapPartner: engine.Piece{Kind: engine.Pawn, Color: engine.White},
+	}
+	state, err := engine.ParseFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.countMove(state, res)
+	if s.SwappedMoves != 1 || s.Swaps != 1 || s.BackRankPawns != 1 || s.KingExposures != 0 {
+		t.Fatalf("unexpected counts %+v", s)
+	}
+
+	res.Swapped = false
+	res.Suppression = engine.SuppressedByCheck
+	s.countMove(state, res)
+	if s.SwappedMoves != 1 || s.Suppressed[engine.SuppressedByCheck.String()] != 1 {
+		t.Fatalf("expected a suppressed swap, got %+v", s)
+	}
+}
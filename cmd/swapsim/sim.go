@@ -0,0 +1,247 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+// Move choice policies.
+const (
+	policyRandom  = "random"
+	policyCapture = "capture"
+)
+
+// simulation describes the playouts to run.
+type simulation struct {
+	fen      string
+	rules    engine.Rules
+	policy   string
+	seed     int64
+	maxPlies int
+}
+
+// stats aggregates playouts. Every field but the rates is a count that adds
+// up across games, so the totals do not depend on how the games were spread
+// over the workers.
+type stats struct {
+	Games      int     `json:"games"`
+	Plies      int     `json:"plies"`
+	MeanLength float64 `json:"mean_length"`
+	MaxLength  int     `json:"max_length"`
+
+	WhiteWins int `json:"white_wins"`
+	BlackWins int `json:"black_wins"`
+	Draws     int `json:"draws"`
+	// WhiteScore is White's mean points per game, measuring the first-move
+	// advantage.
+	WhiteScore float64        `json:"white_score"`
+	Outcomes   map[string]int `json:"outcomes"`
+
+	// SwappedMoves counts moves with at least one swap and Swaps every swap
+	// step, which differ when the rules chain several swaps.
+	SwappedMoves int     `json:"swapped_moves"`
+	Swaps        int     `json:"swaps"`
+	SwapRate     float64 `json:"swap_rate"`
+	// Suppressed counts the moves without a swap by reason.
+	Suppressed      map[string]int `json:"suppressed"`
+	SuppressionRate float64        `json:"suppression_rate"`
+
+	// KingExposures counts swapped moves that left the mover's own king
+	// attacked; the rate is per swapped move.
+	KingExposures    int     `json:"king_exposures"`
+	KingExposureRate float64 `json:"king_exposure_rate"`
+	// BackRankPawns counts pawns a swap step put on the first or last rank,
+	// including those it promoted; the rate is per swap step.
+	BackRankPawns    int     `json:"back_rank_pawns"`
+	BackRankPawnRate float64 `json:"back_rank_pawn_rate"`
+	SwapPromotions   int     `json:"swap_promotions"`
+}
+
+func newStats() *stats {
+	return &stats{Outcomes: map[string]int{}, Suppressed: map[string]int{}}
+}
+
+// add adds the counts of o to s.
+func (s *stats) add(o *stats) {
+	s.Games += o.Games
+	s.Plies += o.Plies
+	s.MaxLength = max(s.MaxLength, o.MaxLength)
+	s.WhiteWins += o.WhiteWins
+	s.BlackWins += o.BlackWins
+	s.Draws += o.Draws
+	for k, v := range o.Outcomes {
+		s.Outcomes[k] += v
+	}
+	s.SwappedMoves += o.SwappedMoves
+	s.Swaps += o.Swaps
+	for k, v := range o.Suppressed {
+		s.Suppressed[k] += v
+	}
+	s.KingExposures += o.KingExposures
+	s.BackRankPawns += o.BackRankPawns
+	s.SwapPromotions += o.SwapPromotions
+}
+
+// finish computes the rates from the counts.
+func (s *stats) finish() {
+	ratio := func(a, b int) float64 {
+		if b == 0 {
+			return 0
+		}
+		return float64(a) / float64(b)
+	}
+	suppressed := 0
+	for _, v := range s.Suppressed {
+		suppressed += v
+	}
+	s.MeanLength = ratio(s.Plies, s.Games)
+	s.WhiteScore = ratio(2*s.WhiteWins+s.Draws, 2*s.Games)
+	s.SwapRate = ratio(s.SwappedMoves, s.Plies)
+	s.SuppressionRate = ratio(suppressed, s.Plies)
+	s.KingExposureRate = ratio(s.KingExposures, s.SwappedMoves)
+	s.BackRankPawnRate = ratio(s.BackRankPawns, s.Swaps)
+}
+
+// run plays games playouts on concurrency workers and returns their totals.
+func (sim simulation) run(games, concurrency int) (*stats, error) {
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := 0; i < games; i++ {
+			indexes <- i
+		}
+	}()
+
+	totals := make([]*stats, concurrency)
+	errs := make([]error, concurrency)
+	var wg sync.WaitGroup
+	for w := range totals {
+		totals[w] = newStats()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if errs[w] != nil {
+					continue
+				}
+				errs[w] = sim.playout(i, totals[w])
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := newStats()
+	for w, t := range totals {
+		if errs[w] != nil {
+			return nil, errs[w]
+		}
+		total.add(t)
+	}
+	total.finish()
+	return total, nil
+}
+
+// playout plays game i with engine.ApplyMoveResult until engine.GameOutcome
+// ends it or it reaches maxPlies, and adds it to s. The swap seed is
+// sim.seed+i, and the moves are drawn from a generator seeded with sim.seed
+// and i, so every game can be replayed on its own.
+func (sim simulation) playout(i int, s *stats) error {
+	state, err := engine.ParseFEN(sim.fen)
+	if err != nil {
+		return err
+	}
+	rules := sim.rules
+	state.Rules = &rules
+	state.RandSeed = sim.seed + int64(i)
+	rng := rand.New(rand.NewPCG(uint64(sim.seed), uint64(i)))
+
+	plies := 0
+	outcome := engine.OutcomeNone
+	for {
+		if outcome = engine.GameOutcome(state); outcome != engine.OutcomeNone || (sim.maxPlies > 0 && plies >= sim.maxPlies) {
+			break
+		}
+		res, err := engine.ApplyMoveResult(state, sim.choose(state, rng))
+		if err != nil {
+			return err
+		}
+		plies++
+		s.countMove(state, res)
+	}
+
+	s.Games++
+	s.Plies += plies
+	s.MaxLength = max(s.MaxLength, plies)
+	switch {
+	case outcome == engine.OutcomeCheckmate || outcome == engine.OutcomeKingCaptured:
+		// the side to move has lost
+		if state.Turn == engine.White {
+			s.BlackWins++
+		} else {
+			s.WhiteWins++
+		}
+		s.Outcomes[outcome.String()]++
+	case outcome == engine.OutcomeNone:
+		s.Draws++
+		s.Outcomes["ply limit"]++
+	default:
+		s.Draws++
+		s.Outcomes[outcome.String()]++
+	}
+	return nil
+}
+
+// choose picks the next move by the simulation's policy.
+func (sim simulation) choose(state *engine.GameState, rng *rand.Rand) engine.Move {
+	moves := engine.LegalMoves(state)
+	if sim.policy == policyCapture {
+		captures := moves[:0:0]
+		for _, m := range moves {
+			if state.Board.Squares[m.To.File][m.To.Rank] != nil {
+				captures = append(captures, m)
+			}
+		}
+		if len(captures) > 0 {
+			moves = captures
+		}
+	}
+	return moves[rng.IntN(len(moves))]
+}
+
+// countMove adds the swap behavior of one applied move to s.
+func (s *stats) countMove(state *engine.GameState, res engine.MoveResult) {
+	if !res.Swapped {
+		if res.Suppression != engine.SwapNotSuppressed {
+			s.Suppressed[res.Suppression.String()]++
+		}
+		return
+	}
+
+	s.SwappedMoves++
+	if engine.IsInCheck(state, res.Mover) {
+		s.KingExposures++
+	}
+	moved := res.Piece.Kind
+	if res.Promoted {
+		moved = res.Promotion
+	}
+	steps := append([]engine.SwapStep{{A: res.SwapA, B: res.SwapB, Partner: res.SwapPartner, Promoted: res.SwapPromoted}}, res.ExtraSwaps...)
+	for _, step := range steps {
+		s.Swaps++
+		if step.Promoted {
+			s.SwapPromotions++
+		}
+		if moved == engine.Pawn && isBackRank(step.B) {
+			s.BackRankPawns++
+		}
+		if step.Partner.Kind == engine.Pawn && isBackRank(step.A) {
+			s.BackRankPawns++
+		}
+	}
+}
+
+func isBackRank(pos engine.Position) bool {
+	return pos.Rank == 0 || pos.Rank == 7
+}
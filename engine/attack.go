@@ -0,0 +1,154 @@
+package engine
+
+import "math/bits"
+
+// Attack tables are indexed by square number file*8+rank, matching the
+// Squares[file][rank] layout, and are filled once at start-up so that attack
+// and move generation never recompute offsets, bounds or directions.
+var (
+	// knightTargets and kingTargets list the squares a knight or king on a
+	// square reaches, in the order moves are generated. knightAttacks and
+	// kingAttacks hold the same squares as masks. Both relations are
+	// symmetric, so the same squares are those a knight or king must stand on
+	// to attack the square.
+	knightTargets [64][]Position
+	kingTargets   [64][]Position
+	knightAttacks [64]uint64
+	kingAttacks   [64]uint64
+	// pawnAttackers[c][sq] holds the squares from which a pawn of color c attacks sq.
+	pawnAttackers [2][64]uint64
+	// rayMasks[d][sq] holds the squares from sq towards the board edge in
+	// direction d: 0-3 follow rookDirs and 4-7 follow bishopDirs.
+	rayMasks [8][64]uint64
+	// rayAscending[d] reports whether square numbers grow along direction d,
+	// so that the nearest square of a ray is its lowest bit.
+	rayAscending [8]bool
+	// queenLines[sq] holds every square on a rank, file or diagonal through sq.
+	queenLines [64]uint64
+)
+
+func init() {
+	var dirs [8][2]int
+	copy(dirs[:4], rookDirs[:])
+	copy(dirs[4:], bishopDirs[:])
+	for d, dir := range dirs {
+		rayAscending[d] = dir[0]*8+dir[1] > 0
+	}
+
+	for f := 0; f < 8; f++ {
+		for r := 0; r < 8; r++ {
+			sq := squareIndex(Position{File: f, Rank: r})
+			for _, off := range knightOffsets {
+				if to := (Position{File: f + off[0], Rank: r + off[1]}); onBoard(to.File, to.Rank) {
+					knightTargets[sq] = append(knightTargets[sq], to)
+					knightAttacks[sq] |= squareBit(to)
+				}
+			}
+			for _, off := range kingOffsets {
+				if to := (Position{File: f + off[0], Rank: r + off[1]}); onBoard(to.File, to.Rank) {
+					kingTargets[sq] = append(kingTargets[sq], to)
+					kingAttacks[sq] |= squareBit(to)
+				}
+			}
+			// a white pawn attacks sq from the rank below, a black pawn from the rank above
+			for _, df := range [2]int{-1, 1} {
+				if onBoard(f+df, r-1) {
+					pawnAttackers[White][sq] |= squareBit(Position{File: f + df, Rank: r - 1})
+				}
+				if onBoard(f+df, r+1) {
+					pawnAttackers[Black][sq] |= squareBit(Position{File: f + df, Rank: r + 1})
+				}
+			}
+			for d, dir := range dirs {
+				for tf, tr := f+dir[0], r+dir[1]; onBoard(tf, tr); tf, tr = tf+dir[0], tr+dir[1] {
+					rayMasks[d][sq] |= squareBit(Position{File: tf, Rank: tr})
+				}
+				queenLines[sq] |= rayMasks[d][sq]
+			}
+		}
+	}
+}
+
+// nearestSquare returns the square of mask nearest to the start of a ray in
+// direction d; mask must hold squares of that ray.
+func nearestSquare(d int, mask uint64) int {
+	if rayAscending[d] {
+		return bits.TrailingZeros64(mask)
+	}
+	return 63 - bits.LeadingZeros64(mask)
+}
+
+// rayAttacks returns the squares a slider on sq reaches in direction d, up to
+// and including the first occupied square in occ.
+func rayAttacks(d, sq int, occ uint64) uint64 {
+	attacks := rayMasks[d][sq]
+	if blockers := attacks & occ; blockers != 0 {
+		attacks &^= rayMasks[d][nearestSquare(d, blockers)]
+	}
+	return attacks
+}
+
+func rookAttacks(sq int, occ uint64) uint64 {
+	return rayAttacks(0, sq, occ) | rayAttacks(1, sq, occ) | rayAttacks(2, sq, occ) | rayAttacks(3, sq, occ)
+}
+
+func bishopAttacks(sq int, occ uint64) uint64 {
+	return rayAttacks(4, sq, occ) | rayAttacks(5, sq, occ) | rayAttacks(6, sq, occ) | rayAttacks(7, sq, occ)
+}
+
+// attackers returns the pieces of color by that attack sq when the squares in
+// occ are occupied. Passing an occupancy other than the board's lets callers
+// test a move without playing it.
+func (b *bitboards) attackers(sq int, by Color, occ uint64) uint64 {
+	p := &b.pieces[by]
+	attackers := knightAttacks[sq]&p[Knight] | kingAttacks[sq]&p[King] | pawnAttackers[by][sq]&p[Pawn]
+	if sliders := p[Rook] | p[Queen]; sliders != 0 {
+		attackers |= rookAttacks(sq, occ) & sliders
+	}
+	if sliders := p[Bishop] | p[Queen]; sliders != 0 {
+		attackers |= bishopAttacks(sq, occ) & sliders
+	}
+	return attackers
+}
+
+// between returns the squares strictly between a and b, or 0 when they do not
+// share a rank, file or diagonal.
+func between(a, b int) uint64 {
+	for d := range rayMasks {
+		if rayMasks[d][a]&(1<<b) != 0 {
+			return rayMasks[d][a] &^ rayMasks[d][b] &^ (1 << b)
+		}
+	}
+	return 0
+}
+
+// squareAttacked reports whether `pos` is attacked by any piece of color `by`.
+// It looks outwards from pos through the attack tables instead of testing
+// every piece on the board.
+func squareAttacked(state *GameState, pos Position, by Color) bool {
+	b := &state.bitboards
+	return b.attackers(squareIndex(pos), by, b.all()) != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// findKingPosition returns the square of color's king, the first one in
+// Squares order should a board hold several.
+func findKingPosition(state *GameState, color Color) (Position, bool) {
+	kings := state.bitboards.pieces[color][King]
+	if kings == 0 {
+		return Position{}, false
+	}
+	return squarePosition(lowestSquare(kings)), true
+}
+
+// SquareAttacked reports whether any piece of color by attacks pos.
+func SquareAttacked(state *GameState, pos Position, by Color) bool {
+	state.sync()
+	return squareAttacked(state, pos, by)
+}
@@ -0,0 +1,118 @@
+package engine
+
+import "testing"
+
+// attackedSlow is the straightforward reference for squareAttacked: it asks
+// every piece of color by whether it reaches pos.
+func attackedSlow(state *GameState, pos Position, by Color) bool {
+	for f := 0; f < 8; f++ {
+		for r := 0; r < 8; r++ {
+			p := state.Board.Squares[f][r]
+			if p == nil || p.Color != by || (f == pos.File && r == pos.Rank) {
+				continue
+			}
+			df, dr := pos.File-f, pos.Rank-r
+			straight := df == 0 || dr == 0
+			diagonal := abs(df) == abs(dr)
+			from := Position{File: f, Rank: r}
+			var hit bool
+			switch p.Kind {
+			case Pawn:
+				forward := 1
+				if by == Black {
+					forward = -1
+				}
+				hit = abs(df) == 1 && dr == forward
+			case Knight:
+				hit = abs(df)*abs(dr) == 2
+			case King:
+				hit = abs(df) <= 1 && abs(dr) <= 1
+			case Rook:
+				hit = straight && pathClear(&state.Board, from, pos)
+			case Bishop:
+				hit = diagonal && pathClear(&state.Board, from, pos)
+			case Queen:
+				hit = (straight || diagonal) && pathClear(&state.Board, from, pos)
+			}
+			if hit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathClear reports whether every square strictly between from and to is
+// empty. The squares must share a file, rank or diagonal.
+func pathClear(b *Board, from, to Position) bool {
+	sF, sR := sign(to.File-from.File), sign(to.Rank-from.Rank)
+	for f, r := from.File+sF, from.Rank+sR; f != to.File || r != to.Rank; f, r = f+sF, r+sR {
+		if b.Squares[f][r] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func TestSquareAttackedMatchesReference(t *testing.T) {
+	s := mustParseFEN(t, kiwipete)
+	for ply := 0; ply < 80; ply++ {
+		for f := 0; f < 8; f++ {
+			for r := 0; r < 8; r++ {
+				pos := Position{File: f, Rank: r}
+				for _, by := range []Color{White, Black} {
+					if got, want := squareAttacked(s, pos, by), attackedSlow(s, pos, by); got != want {
+						t.Fatalf("ply %d, %s attacked by %s: got %v, want %v in %s", ply, SquareString(pos), by, got, want, s.FEN())
+					}
+				}
+			}
+		}
+		moves := LegalMoves(s)
+		if len(moves) == 0 || GameOutcome(s) != OutcomeNone {
+			break
+		}
+		if _, err := ApplyMoveResult(s, moves[(ply*5)%len(moves)]); err != nil {
+			t.Fatalf("ply %d: ApplyMoveResult returned error: %v", ply, err)
+		}
+	}
+}
+
+func TestBitboardsFollowDirectEdits(t *testing.T) {
+	s := NewGame()
+	s.Board.Squares[4][0], s.Board.Squares[4][3] = nil, s.Board.Squares[4][0]
+	if got := len(LegalMovesFrom(s, Position{File: 4, Rank: 3})); got != 8 {
+		t.Fatalf("expected the king moved to e4 to have 8 moves, got %d", got)
+	}
+	if pos, ok := findKingPosition(s, White); !ok || pos != (Position{File: 4, Rank: 3}) {
+		t.Fatalf("expected the moved king on e4, got %s (found %v)", SquareString(pos), ok)
+	}
+	s.Board.Squares[4][3] = nil
+	if !IsInCheck(s, White) || GameOutcome(s) != OutcomeKingCaptured {
+		t.Fatalf("expected a missing white king after removing it")
+	}
+	if _, ok := findKingPosition(s, White); ok {
+		t.Fatalf("expected no white king after removing it")
+	}
+
+	// a piece changed in place needs Rehash
+	s = NewGame()
+	s.Board.Squares[3][0].Kind = Rook
+	s.Rehash()
+	if got := len(LegalMovesFrom(s, Position{File: 3, Rank: 0})); got != 0 {
+		t.Fatalf("expected the boxed-in rook on d1 to have no moves, got %d", got)
+	}
+	if got, want := s.Hash(), mustParseFEN(t, s.FEN()).Hash(); got != want {
+		t.Fatalf("expected Rehash to rehash the changed piece, got %#x want %#x", got, want)
+	}
+}
@@ -1,10 +1,121 @@
 package engine
 
+import "math/bits"
+
 type Position struct {
 	File int // 0–7
 	Rank int // 0–7
 }
 
+// Board is indexed Squares[file][rank]. It is the public view of the
+// bitboards the engine plays on, which every move keeps in step with it.
+// Callers may read Squares and put pieces on or take them off directly. The
+// engine notices such edits by comparing Squares with the pieces it placed
+// itself, and then rebuilds the bitboards and the placement part of
+// GameState.Hash. A Piece on the board must not be changed in place: replace
+// it with a new Piece, or call GameState.Rehash afterwards.
 type Board struct {
 	Squares [8][8]*Piece
 }
+
+// bitboards is the value representation of the board that attack detection
+// and move generation work on. Bit squareIndex(pos) of each mask stands for
+// pos, so iterating a mask from its lowest bit visits squares in the
+// file-major order of Squares.
+type bitboards struct {
+	// pieces[c][k] holds the squares of color c's pieces of kind k.
+	pieces [2][6]uint64
+	// occupied[c] holds the squares of all of color c's pieces.
+	occupied [2]uint64
+}
+
+func squareIndex(pos Position) int {
+	return pos.File*8 + pos.Rank
+}
+
+func squarePosition(sq int) Position {
+	return Position{File: sq >> 3, Rank: sq & 7}
+}
+
+func squareBit(pos Position) uint64 {
+	return 1 << squareIndex(pos)
+}
+
+// lowestSquare returns the square of the lowest bit of a non-empty mask.
+func lowestSquare(mask uint64) int {
+	return bits.TrailingZeros64(mask)
+}
+
+func (b *bitboards) all() uint64 {
+	return b.occupied[White] | b.occupied[Black]
+}
+
+// put adds p on sq to the masks; p may be nil.
+func (b *bitboards) put(p *Piece, sq int) {
+	if p == nil {
+		return
+	}
+	bit := uint64(1) << sq
+	b.pieces[p.Color][p.Kind] |= bit
+	b.occupied[p.Color] |= bit
+}
+
+// remove takes p on sq off the masks; p may be nil.
+func (b *bitboards) remove(p *Piece, sq int) {
+	if p == nil {
+		return
+	}
+	bit := uint64(1) << sq
+	b.pieces[p.Color][p.Kind] &^= bit
+	b.occupied[p.Color] &^= bit
+}
+
+// Rehash rebuilds the bitboards and the placement part of Hash from
+// Board.Squares. It is only needed after a Piece on the board was changed in
+// place; see Board.
+func (g *GameState) Rehash() {
+	g.bitboards = bitboards{}
+	g.boardHash = 0
+	for f := 0; f < 8; f++ {
+		for r := 0; r < 8; r++ {
+			pos := Position{File: f, Rank: r}
+			p := g.Board.Squares[f][r]
+			g.bitboards.put(p, squareIndex(pos))
+			g.boardHash ^= pieceHash(p, pos)
+		}
+	}
+	g.placed = g.Board.Squares
+}
+
+// sync calls Rehash when Board.Squares no longer holds the pieces the engine
+// left there, i.e. after a direct edit. Comparing the two arrays is much
+// cheaper than rebuilding the board. Every exported function that works on the
+// bitboards or the hash calls it first.
+func (g *GameState) sync() {
+	if g.Board.Squares != g.placed {
+		g.Rehash()
+	}
+}
+
+// setSquare puts p on pos and updates the bitboards and the placement hash.
+func (g *GameState) setSquare(pos Position, p *Piece) {
+	sq := squareIndex(pos)
+	old := g.Board.Squares[pos.File][pos.Rank]
+	g.bitboards.remove(old, sq)
+	g.bitboards.put(p, sq)
+	g.boardHash ^= pieceHash(old, pos) ^ pieceHash(p, pos)
+	g.Board.Squares[pos.File][pos.Rank] = p
+	g.placed[pos.File][pos.Rank] = p
+}
+
+// setKind changes the kind of the piece on pos in place, as promotions do, and
+// updates the bitboards and the placement hash.
+func (g *GameState) setKind(pos Position, kind PieceKind) {
+	sq := squareIndex(pos)
+	p := g.Board.Squares[pos.File][pos.Rank]
+	g.bitboards.remove(p, sq)
+	g.boardHash ^= pieceHash(p, pos)
+	p.Kind = kind
+	g.bitboards.put(p, sq)
+	g.boardHash ^= pieceHash(p, pos)
+}
@@ -0,0 +1,133 @@
+// Package bot contains computer opponents for Swapchess.
+package bot
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+// Bot picks moves for the side to move.
+type Bot interface {
+	// Name identifies the bot in the UI.
+	Name() string
+	// ChooseMove returns a legal move for the side to move. It does not
+	// change state.
+	ChooseMove(state *engine.GameState) (engine.Move, error)
+}
+
+// ContextBot is a Bot whose search can be stopped early.
+type ContextBot interface {
+	Bot
+	// ChooseMoveContext is ChooseMove with a search that ends when ctx is
+	// done, returning the best move found by then.
+	ChooseMoveContext(ctx context.Context, state *engine.GameState) (engine.Move, error)
+}
+
+// ChooseMoveContext asks b for a move, passing ctx on when b is a ContextBot.
+func ChooseMoveContext(ctx context.Context, b Bot, state *engine.GameState) (engine.Move, error) {
+	if cb, ok := b.(ContextBot); ok {
+		return cb.ChooseMoveContext(ctx, state)
+	}
+	return b.ChooseMove(state)
+}
+
+var ErrNoMoves = errors.New("no legal moves")
+
+// winScore outweighs any material difference.
+const winScore = 1000
+
+// pieceValues are the classic material values in pawns. The king's value
+// only matters for the king captures swaps make possible.
+var pieceValues = [...]float64{
+	engine.Pawn:   1,
+	engine.Knight: 3,
+	engine.Bishop: 3,
+	engine.Rook:   5,
+	engine.Queen:  9,
+	engine.King:   winScore,
+}
+
+// Simple is a one-ply bot that knows about swaps: it scores each move by the
+// material it expects after averaging over every swap the move can trigger
+// and letting the opponent make its most valuable capture in reply.
+type Simple struct{}
+
+func (Simple) Name() string { return "simple" }
+
+func (Simple) ChooseMove(state *engine.GameState) (engine.Move, error) {
+	// LegalMoves and MakeMove work in place, so search on a copy.
+	s := state.Clone()
+	moves := engine.LegalMoves(s)
+	if len(moves) == 0 {
+		return engine.Move{}, ErrNoMoves
+	}
+
+	me := s.Turn
+	bestScore := math.Inf(-1)
+	var best []engine.Move
+	for _, move := range moves {
+		outcomes, err := engine.SwapOutcomes(s, move)
+		if err != nil {
+			return engine.Move{}, err
+		}
+		score := 0.0
+		for _, o := range outcomes {
+			_, u, err := engine.MakeMoveWithSwaps(s, move, o.Targets)
+			if err != nil {
+				return engine.Move{}, err
+			}
+			score += o.Probability * replyScore(s, me)
+			engine.UnmakeMove(s, &u)
+		}
+		switch {
+		case score > bestScore+1e-9:
+			bestScore = score
+			best = append(best[:0], move)
+		case score > bestScore-1e-9:
+			best = append(best, move)
+		}
+	}
+	// vary the choice between equal moves by position, keeping it reproducible
+	return best[s.Hash()%uint64(len(best))], nil
+}
+
+// replyScore scores the position for me once the opponent, who is to move,
+// has made its most valuable capture.
+func replyScore(s *engine.GameState, me engine.Color) float64 {
+	switch outcome := engine.GameOutcome(s); {
+	case outcome == engine.OutcomeCheckmate || outcome == engine.OutcomeKingCaptured:
+		return winScore
+	case outcome.IsDraw():
+		return 0
+	}
+
+	threat := 0.0
+	for _, reply := range engine.LegalMoves(s) {
+		if p := s.Board.Squares[reply.To.File][reply.To.Rank]; p != nil && pieceValues[p.Kind] > threat {
+			threat = pieceValues[p.Kind]
+		}
+	}
+	return material(s, me) - threat
+}
+
+// material is the piece value balance from color's point of view.
+func material(s *engine.GameState, color engine.Color) float64 {
+	total := 0.0
+	for f := 0; f < 8; f++ {
+		for r := 0; r < 8; r++ {
+			p := s.Board.Squares[f][r]
+			if p == nil {
+				continue
+			}
+			if p.Color == color {
+				total += pieceValues[p.Kind]
+			} else {
+				total -= pieceValues[p.Kind]
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+func mustParseFEN(t *testing.T, fen string) *engine.GameState {
+	t.Helper()
+	s, err := engine.ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q) returned error: %v", fen, err)
+	}
+	return s
+}
+
+func square(t *testing.T, text string) engine.Position {
+	t.Helper()
+	pos, err := engine.ParseSquare(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pos
+}
+
+func TestSimpleDeliversMate(t *testing.T) {
+	// Ra8 is mate; checking moves never swap, so nothing can spoil it.
+	s := mustParseFEN(t, "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1")
+	before := s.FEN()
+	move, err := Simple{}.ChooseMove(s)
+	if err != nil {
+		t.Fatalf("ChooseMove returned error: %v", err)
+	}
+	if move.From != square(t, "a1") || move.To != square(t, "a8") {
+		t.Fatalf("expected a1a8, got %+v", move)
+	}
+	if s.FEN() != before {
+		t.Fatalf("ChooseMove changed the state to %s", s.FEN())
+	}
+}
+
+func TestSimpleTakesHangingQueen(t *testing.T) {
+	s := mustParseFEN(t, "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	move, err := Simple{}.ChooseMove(s)
+	if err != nil {
+		t.Fatalf("ChooseMove returned error: %v", err)
+	}
+	if move.To != square(t, "d5") {
+		t.Fatalf("expected the queen on d5 to be taken, got %+v", move)
+	}
+}
+
+func TestSimpleReportsNoMoves(t *testing.T) {
+	s := mustParseFEN(t, "R5k1/5ppp/8/8/8/8/8/4K3 b - - 0 1")
+	if _, err := (Simple{}).ChooseMove(s); !errors.Is(err, ErrNoMoves) {
+		t.Fatalf("expected ErrNoMoves when mated, got %v", err)
+	}
+}
+
+func TestSimplePlaysLegalGames(t *testing.T) {
+	s := engine.NewGame()
+	s.RandSeed = 5
+	for ply := 0; ply < 40 && engine.GameOutcome(s) == engine.OutcomeNone; ply++ {
+		move, err := Simple{}.ChooseMove(s)
+		if err != nil {
+			t.Fatalf("ply %d: ChooseMove returned error: %v", ply, err)
+		}
+		if err := engine.ApplyMove(s, move); err != nil {
+			t.Fatalf("ply %d: bot chose an illegal move %+v: %v", ply, move, err)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package bot
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/engine/eval"
+)
+
+// DefaultDepth is the search depth of an Expectimax without limits.
+const DefaultDepth = 2
+
+// MaxDepth is the deepest iteration of a search limited only by time, nodes
+// or its context.
+const MaxDepth = 64
+
+// SearchLimits bounds a search. The search deepens one ply at a time until
+// it reaches Depth or another limit stops it, and then returns the best move
+// of the deepest iteration, counting the root moves that iteration finished.
+type SearchLimits struct {
+	// Depth is the deepest iteration; 0 means MaxDepth when MoveTime, Nodes
+	// or Context can stop the search, and DefaultDepth otherwise.
+	Depth int
+	// Nodes stops the search once it has visited this many positions;
+	// 0 means no limit.
+	Nodes int
+	// MoveTime stops the search once it has run this long; 0 means no limit.
+	MoveTime time.Duration
+	// Context stops the search when it is done; nil means never.
+	Context context.Context
+}
+
+// depth returns the deepest iteration to run.
+func (l SearchLimits) depth() int {
+	switch {
+	case l.Depth > 0:
+		return min(l.Depth, MaxDepth)
+	case l.Nodes > 0 || l.MoveTime > 0 || l.Context != nil:
+		return MaxDepth
+	}
+	return DefaultDepth
+}
+
+// Expectimax searches the game tree as alternating move and chance nodes:
+// the side to move picks the best move, and the swap that follows is averaged
+// over every SwapOutcome by its probability. Moves whose swap is suppressed
+// (checks and replies to checks under the classic rules) have a single
+// outcome and play like ordinary minimax. Chance nodes are pruned with Star1,
+// the alpha-beta generalization of *-minimax, using the bounded evaluation.
+// Both kinds of node are cached in a TranspositionTable, and the search
+// deepens iteratively, trying the best move found so far first.
+type Expectimax struct {
+	// Depth is the number of plies to search; see SearchLimits.
+	Depth int
+	// MaxNodes stops the search once it has visited this many positions;
+	// 0 means no limit.
+	MaxNodes int
+	// MoveTime stops the search once it has run this long; 0 means no limit.
+	MoveTime time.Duration
+	// Table keeps results between searches; nil gives each search a fresh
+	// table of DefaultTableSize entries.
+	Table *TranspositionTable
+}
+
+// SearchResult reports the outcome of a search.
+type SearchResult struct {
+	Move engine.Move
+	// Score is the expected evaluation in pawns from the point of view
+	// of the side to move; ±winScore means a forced win or loss.
+	Score float64
+	// Depth is the deepest iteration the search finished.
+	Depth int
+	// Nodes counts the positions visited.
+	Nodes int
+	// Complete is false when a limit other than the depth cut the search short.
+	Complete bool
+}
+
+func (Expectimax) Name() string { return "expectimax" }
+
+func (e Expectimax) ChooseMove(state *engine.GameState) (engine.Move, error) {
+	res, err := e.Search(state)
+	return res.Move, err
+}
+
+// ChooseMoveContext is ChooseMove with a search that stops when ctx is done.
+func (e Expectimax) ChooseMoveContext(ctx context.Context, state *engine.GameState) (engine.Move, error) {
+	res, err := e.SearchWithLimits(state, SearchLimits{Depth: e.Depth, Nodes: e.MaxNodes, MoveTime: e.MoveTime, Context: ctx})
+	return res.Move, err
+}
+
+// Search runs the search within the limits of e and reports the chosen move
+// with its score. It does not change state.
+func (e Expectimax) Search(state *engine.GameState) (SearchResult, error) {
+	return e.SearchWithLimits(state, SearchLimits{Depth: e.Depth, Nodes: e.MaxNodes, MoveTime: e.MoveTime})
+}
+
+// SearchWithLimits runs the search within limits instead of those of e.
+func (e Expectimax) SearchWithLimits(state *engine.GameState, limits SearchLimits) (SearchResult, error) {
+	ctx := limits.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if limits.MoveTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.MoveTime)
+		defer cancel()
+	}
+	table := e.Table
+	if table == nil {
+		table = NewTranspositionTable(DefaultTableSize)
+	}
+	table.newSearch()
+
+	sr := &searcher{s: state.Clone(), ctx: ctx, maxNodes: limits.Nodes, table: table}
+	moves := sr.orderedMoves(noMove)
+	if len(moves) == 0 {
+		return SearchResult{}, ErrNoMoves
+	}
+
+	res := SearchResult{Move: moves[0], Score: evaluate(state, 0)}
+	for depth := 1; depth <= limits.depth(); depth++ {
+		best, score := 0, math.Inf(-1)
+		for i, move := range moves {
+			v := sr.chance(move, depth, 0, score, winScore)
+			if sr.aborted {
+				break
+			}
+			if v > score {
+				best, score = i, v
+			}
+		}
+		if !math.IsInf(score, -1) {
+			// the root moves are searched best first, so the best of those
+			// finished is at least as good as the last iteration's choice
+			res.Move, res.Score = moves[best], score
+		}
+		if sr.aborted {
+			break
+		}
+		res.Depth = depth
+		if math.Abs(score) > winScore-MaxDepth {
+			// a forced result does not change with depth
+			break
+		}
+		moves[0], moves[best] = moves[best], moves[0]
+	}
+	res.Nodes = sr.nodes
+	res.Complete = !sr.aborted
+	return res, nil
+}
+
+// searcher holds the state of one search. It plays moves on its own copy of
+// the game with MakeMove and UnmakeMove.
+type searcher struct {
+	s        *engine.GameState
+	ctx      context.Context
+	maxNodes int
+	table    *TranspositionTable
+	nodes    int
+	aborted  bool
+}
+
+// stopped reports whether a limit has been reached, checking the context
+// every few hundred nodes.
+func (sr *searcher) stopped() bool {
+	switch {
+	case sr.aborted:
+	case sr.maxNodes > 0 && sr.nodes >= sr.maxNodes:
+		sr.aborted = true
+	case sr.nodes%256 == 0 && sr.ctx.Err() != nil:
+		sr.aborted = true
+	}
+	return sr.aborted
+}
+
+// negamax returns the value of the position for the side to move within the
+// window (alpha, beta). ply counts the plies from the root, so that faster
+// wins score higher.
+func (sr *searcher) negamax(depth, ply int, alpha, beta float64) float64 {
+	if sr.stopped() {
+		return 0
+	}
+	s := sr.s
+	if engine.IsFiftyMoveDraw(s) || engine.IsInsufficientMaterial(s) || engine.IsThreefoldRepetition(s) {
+		return 0
+	}
+	if !hasKing(s, s.Turn) {
+		// the opponent captured the king, which ends the game
+		return -winScore + float64(ply)
+	}
+	if depth <= 0 {
+		return evaluate(s, ply)
+	}
+
+	key := s.Hash()
+	ttMove := noMove
+	if e, ok := sr.table.probe(key); ok {
+		ttMove = e.move
+		if v, ok := cutoff(e, depth, ply, &alpha, &beta); ok {
+			return v
+		}
+	}
+
+	moves := sr.orderedMoves(ttMove)
+	if len(moves) == 0 {
+		// checkmated, or the king was captured, or stalemate
+		if engine.IsInCheck(s, s.Turn) {
+			return -winScore + float64(ply)
+		}
+		return 0
+	}
+
+	origAlpha := alpha
+	best, bestMove := math.Inf(-1), noMove
+	for _, move := range moves {
+		v := sr.chance(move, depth, ply, alpha, beta)
+		if sr.aborted {
+			return 0
+		}
+		if v > best {
+			best, bestMove = v, packMove(move)
+		}
+		alpha = math.Max(alpha, v)
+		if alpha >= beta {
+			break
+		}
+	}
+	sr.table.store(key, depth, toTable(best, ply), boundOf(best, origAlpha, beta), bestMove)
+	return best
+}
+
+// chance returns the expected value of move for the side playing it, over
+// all of its swap outcomes. Following Star1, each outcome is searched with
+// the narrowest window that can still move the average across alpha or
+// beta, assuming the unsearched outcomes score anywhere in ±winScore. When an
+// outcome falls outside its window the search stops early and returns a
+// bound: at most alpha on a fail low, at least beta on a fail high.
+func (sr *searcher) chance(move engine.Move, depth, ply int, alpha, beta float64) float64 {
+	key := chanceKey(sr.s.Hash(), move)
+	if e, ok := sr.table.probe(key); ok {
+		if v, ok := cutoff(e, depth, ply, &alpha, &beta); ok {
+			return v
+		}
+	}
+
+	outcomes, err := engine.SwapOutcomes(sr.s, move)
+	if err != nil {
+		return -winScore
+	}
+
+	origAlpha := alpha
+	sum, rest := 0.0, 1.0
+	for _, o := range outcomes {
+		if o.Probability <= 0 {
+			continue
+		}
+		rest -= o.Probability
+		lo := (alpha - sum - rest*winScore) / o.Probability
+		hi := (beta - sum + rest*winScore) / o.Probability
+
+		_, u, err := engine.MakeMoveWithSwaps(sr.s, move, o.Targets)
+		if err != nil {
+			return -winScore
+		}
+		sr.nodes++
+		v := -sr.negamax(depth-1, ply+1, -math.Min(hi, winScore), -math.Max(lo, -winScore))
+		engine.UnmakeMove(sr.s, &u)
+		if sr.aborted {
+			return 0
+		}
+
+		sum += o.Probability * v
+		if v <= lo {
+			sum += rest * winScore
+			break
+		}
+		if v >= hi {
+			sum -= rest * winScore
+			break
+		}
+	}
+	sr.table.store(key, depth, toTable(sum, ply), boundOf(sum, origAlpha, beta), noMove)
+	return sum
+}
+
+// cutoff narrows the window (alpha, beta) with a stored entry searched at
+// least depth plies deep, and returns its value when that settles the node.
+func cutoff(e ttEntry, depth, ply int, alpha, beta *float64) (float64, bool) {
+	if int(e.depth) < depth {
+		return 0, false
+	}
+	v := fromTable(e.value, ply)
+	switch e.bound {
+	case boundExact:
+		return v, true
+	case boundLower:
+		*alpha = math.Max(*alpha, v)
+	case boundUpper:
+		*beta = math.Min(*beta, v)
+	}
+	return v, *alpha >= *beta
+}
+
+// boundOf classifies a value returned for the window (alpha, beta).
+func boundOf(v, alpha, beta float64) bound {
+	switch {
+	case v <= alpha:
+		return boundUpper
+	case v >= beta:
+		return boundLower
+	}
+	return boundExact
+}
+
+// toTable converts a forced win or loss scored from the root into one scored
+// from the node at ply, so that it stays correct when the node is reached at
+// another ply; fromTable converts it back.
+func toTable(v float64, ply int) float64 {
+	switch {
+	case v > winScore-MaxDepth:
+		return v + float64(ply)
+	case v < -winScore+MaxDepth:
+		return v - float64(ply)
+	}
+	return v
+}
+
+func fromTable(v float64, ply int) float64 {
+	switch {
+	case v > winScore-MaxDepth:
+		return v - float64(ply)
+	case v < -winScore+MaxDepth:
+		return v + float64(ply)
+	}
+	return v
+}
+
+// orderedMoves returns the legal moves with the stored best move first and
+// then the most valuable captures, which lets the pruning cut more.
+func (sr *searcher) orderedMoves(first packedMove) []engine.Move {
+	moves := engine.LegalMoves(sr.s)
+	victim := func(m engine.Move) float64 {
+		if first != noMove && packMove(m) == first {
+			return math.Inf(1)
+		}
+		if p := sr.s.Board.Squares[m.To.File][m.To.Rank]; p != nil {
+			return pieceValues[p.Kind]
+		}
+		return 0
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return victim(moves[i]) > victim(moves[j]) })
+	return moves
+}
+
+// evaluate scores a quiet leaf for the side to move in pawns with
+// eval.Evaluate, or as a loss when its king has been captured.
+func evaluate(s *engine.GameState, ply int) float64 {
+	if !hasKing(s, s.Turn) {
+		return -winScore + float64(ply)
+	}
+	return float64(eval.Evaluate(s)) / 100
+}
+
+// hasKing reports whether color still has its king.
+func hasKing(s *engine.GameState, color engine.Color) bool {
+	for f := 0; f < 8; f++ {
+		for r := 0; r < 8; r++ {
+			if p := s.Board.Squares[f][r]; p != nil && p.Kind == engine.King && p.Color == color {
+				return true
+			}
+		}
+	}
+	return false
+}
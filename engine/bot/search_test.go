@@ -0,0 +1,212 @@
+package bot
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+// expectimaxValue is the unpruned reference value of the position for the
+// side to move, searched depth plies deep.
+func expectimaxValue(t *testing.T, s *engine.GameState, depth, ply int) float64 {
+	t.Helper()
+	if engine.IsFiftyMoveDraw(s) || engine.IsInsufficientMaterial(s) || engine.IsThreefoldRepetition(s) {
+		return 0
+	}
+	if depth == 0 {
+		return evaluate(s, ply)
+	}
+	moves := engine.LegalMoves(s)
+	if len(moves) == 0 {
+		if engine.IsInCheck(s, s.Turn) {
+			return -winScore + float64(ply)
+		}
+		return 0
+	}
+	best := math.Inf(-1)
+	for _, move := range moves {
+		best = math.Max(best, expectedValue(t, s, move, depth, ply))
+	}
+	return best
+}
+
+func expectedValue(t *testing.T, s *engine.GameState, move engine.Move, depth, ply int) float64 {
+	t.Helper()
+	outcomes, err := engine.SwapOutcomes(s, move)
+	if err != nil {
+		t.Fatalf("SwapOutcomes returned error: %v", err)
+	}
+	value := 0.0
+	for _, o := range outcomes {
+		_, u, err := engine.MakeMoveWithSwaps(s, move, o.Targets)
+		if err != nil {
+			t.Fatalf("MakeMoveWithSwaps returned error: %v", err)
+		}
+		value -= o.Probability * expectimaxValue(t, s, depth-1, ply+1)
+		engine.UnmakeMove(s, &u)
+	}
+	return value
+}
+
+func TestExpectimaxMatchesUnprunedSearch(t *testing.T) {
+	for _, tc := range []struct {
+		fen   string
+		rules string
+	}{
+		{"4k3/8/8/3q4/8/2N5/8/3RK3 w - - 0 1 - 3", "classic"},
+		{"r3k3/1p6/8/3n4/8/2B5/1P6/R3K3 b - - 0 1 - 3", "classic"},
+		{"4k3/2p5/8/3q4/8/2N5/8/3RK3 w - - 0 1 - 3", "gentle"},
+		{"4k3/8/8/3q4/4P3/8/8/3RK3 w - - 0 1 - 3", "double"},
+	} {
+		s := mustParseFEN(t, tc.fen)
+		rules, err := engine.RulesPreset(tc.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Rules = &rules
+
+		res, err := Expectimax{Depth: 2}.Search(s)
+		if err != nil {
+			t.Fatalf("%s: Search returned error: %v", tc.fen, err)
+		}
+		if !res.Complete {
+			t.Fatalf("%s: expected an unlimited search to complete", tc.fen)
+		}
+		want := expectimaxValue(t, s.Clone(), 2, 0)
+		if math.Abs(res.Score-want) > 1e-6 {
+			t.Fatalf("%s (%s): pruned score %v, unpruned %v", tc.fen, tc.rules, res.Score, want)
+		}
+		if got := expectedValue(t, s.Clone(), res.Move, 2, 0); math.Abs(got-want) > 1e-6 {
+			t.Fatalf("%s (%s): chosen move is worth %v, best is %v", tc.fen, tc.rules, got, want)
+		}
+	}
+}
+
+func TestExpectimaxFindsMate(t *testing.T) {
+	s := mustParseFEN(t, "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1")
+	before := s.FEN()
+	res, err := Expectimax{Depth: 2}.Search(s)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if res.Move.To != square(t, "a8") || res.Score < winScore-2 {
+		t.Fatalf("expected mate with a1a8, got %+v", res)
+	}
+	if s.FEN() != before {
+		t.Fatalf("Search changed the state to %s", s.FEN())
+	}
+}
+
+func TestExpectimaxScoresKingCaptureAsWin(t *testing.T) {
+	// a swap left both kings en prise; taking the black one wins at once, and
+	// black may not take back the white king afterwards
+	s := mustParseFEN(t, "7k/8/8/8/8/8/8/4r1KQ w - - 0 1")
+	capture := engine.Move{From: square(t, "h1"), To: square(t, "h8")}
+	// Search stops deepening at a forced win, so search the capture itself
+	for depth := 1; depth <= 3; depth++ {
+		sr := &searcher{s: s.Clone(), ctx: context.Background(), table: NewTranspositionTable(DefaultTableSize)}
+		if v := sr.chance(capture, depth, 0, -winScore, winScore); v != winScore-1 {
+			t.Fatalf("depth %d: expected h1h8 to score %d, got %v", depth, winScore-1, v)
+		}
+	}
+}
+
+func TestExpectimaxRespectsNodeBudget(t *testing.T) {
+	s := engine.NewGame()
+	res, err := Expectimax{Depth: 3, MaxNodes: 500}.Search(s)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if res.Complete || res.Nodes > 500 {
+		t.Fatalf("expected the search to stop at the budget, got %d nodes (complete %v)", res.Nodes, res.Complete)
+	}
+	if err := engine.ApplyMove(s, res.Move); err != nil {
+		t.Fatalf("expected a legal move from a cut-short search, got %v", err)
+	}
+	if math.IsInf(res.Score, 0) {
+		t.Fatalf("expected a finite score, got %v", res.Score)
+	}
+}
+
+func TestExpectimaxReportsNoMoves(t *testing.T) {
+	s := mustParseFEN(t, "R5k1/5ppp/8/8/8/8/8/4K3 b - - 0 1")
+	if _, err := (Expectimax{}).ChooseMove(s); err != ErrNoMoves {
+		t.Fatalf("expected ErrNoMoves, got %v", err)
+	}
+}
+
+func TestExpectimaxReusesItsTable(t *testing.T) {
+	s := mustParseFEN(t, "r3k3/1p6/8/3n4/8/2B5/1P6/R3K3 b - - 0 1 - 3")
+	fresh, err := Expectimax{Depth: 2}.Search(s)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	e := Expectimax{Depth: 2, Table: NewTranspositionTable(1 << 12)}
+	for i := 0; i < 2; i++ {
+		res, err := e.Search(s)
+		if err != nil {
+			t.Fatalf("Search returned error: %v", err)
+		}
+		if math.Abs(res.Score-fresh.Score) > 1e-6 {
+			t.Fatalf("search %d with a shared table scored %v, expected %v", i, res.Score, fresh.Score)
+		}
+		if i == 1 && res.Nodes >= fresh.Nodes {
+			t.Fatalf("expected the filled table to save work, got %d nodes against %d", res.Nodes, fresh.Nodes)
+		}
+	}
+}
+
+func TestSearchDeepensIteratively(t *testing.T) {
+	s := engine.NewGame()
+	res, err := Expectimax{}.SearchWithLimits(s, SearchLimits{Depth: 1})
+	if err != nil {
+		t.Fatalf("SearchWithLimits returned error: %v", err)
+	}
+	if res.Depth != 1 || !res.Complete {
+		t.Fatalf("expected a complete depth 1 search, got %+v", res)
+	}
+
+	// a forced mate ends the deepening early
+	s = mustParseFEN(t, "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1")
+	res, err = Expectimax{}.SearchWithLimits(s, SearchLimits{Depth: 6})
+	if err != nil {
+		t.Fatalf("SearchWithLimits returned error: %v", err)
+	}
+	if res.Depth != 2 || res.Move.To != square(t, "a8") {
+		t.Fatalf("expected mate in one at depth 2, got %+v", res)
+	}
+}
+
+func TestSearchStopsAtMoveTime(t *testing.T) {
+	s := engine.NewGame()
+	start := time.Now()
+	res, err := Expectimax{}.SearchWithLimits(s, SearchLimits{MoveTime: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("SearchWithLimits returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the search to stop after 50ms, took %v", elapsed)
+	}
+	if res.Complete {
+		t.Fatalf("expected the search to be cut short, got %+v", res)
+	}
+	if err := engine.ApplyMove(s, res.Move); err != nil {
+		t.Fatalf("expected a legal move, got %v", err)
+	}
+}
+
+func TestSearchStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := engine.NewGame()
+	move, err := Expectimax{Depth: 4}.ChooseMoveContext(ctx, s)
+	if err != nil {
+		t.Fatalf("ChooseMoveContext returned error: %v", err)
+	}
+	if err := engine.ApplyMove(s, move); err != nil {
+		t.Fatalf("expected a legal move from a cancelled search, got %v", err)
+	}
+}
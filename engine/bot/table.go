@@ -0,0 +1,115 @@
+package bot
+
+import "github.com/divijg19/Swapchess/engine"
+
+// DefaultTableSize is the number of entries of the table a search creates
+// when it is not given one.
+const DefaultTableSize = 1 << 16
+
+// bound tells how a stored value relates to the true value of its node.
+type bound uint8
+
+const (
+	boundExact bound = iota
+	// boundLower means the true value is at least the stored one (a fail high).
+	boundLower
+	// boundUpper means the true value is at most the stored one (a fail low).
+	boundUpper
+)
+
+// ttEntry is one slot of a TranspositionTable. A zero key marks an empty slot.
+type ttEntry struct {
+	key   uint64
+	value float64
+	move  packedMove
+	depth int8
+	bound bound
+	age   uint8
+}
+
+// TranspositionTable caches search results by position hash so that
+// positions reached again, by transposition or in a later iteration, need
+// not be searched twice. It holds both decision nodes, keyed on Hash, and
+// chance nodes, keyed on Hash mixed with the move whose swaps they average.
+//
+// The table has a fixed size. Each hash maps to a bucket of two slots: one
+// keeps the deepest result of the current search and the other always takes
+// the newest, so deep results survive without the table filling up with
+// stale ones. A table is not safe for concurrent use, and since the hash does
+// not cover the rules it must not be shared between games with different
+// rules.
+type TranspositionTable struct {
+	entries []ttEntry
+	mask    uint64
+	age     uint8
+}
+
+// NewTranspositionTable returns a table with room for about size entries,
+// rounded down to a power of two.
+func NewTranspositionTable(size int) *TranspositionTable {
+	buckets := 1
+	for buckets*4 <= size {
+		buckets *= 2
+	}
+	return &TranspositionTable{entries: make([]ttEntry, 2*buckets), mask: uint64(buckets - 1)}
+}
+
+// Clear empties the table.
+func (t *TranspositionTable) Clear() {
+	clear(t.entries)
+	t.age = 0
+}
+
+// newSearch marks the entries stored so far as older than those to come, so
+// that they are the first to be replaced.
+func (t *TranspositionTable) newSearch() {
+	t.age++
+}
+
+func (t *TranspositionTable) bucket(key uint64) []ttEntry {
+	i := 2 * (key & t.mask)
+	return t.entries[i : i+2]
+}
+
+// probe returns the entry stored for key.
+func (t *TranspositionTable) probe(key uint64) (ttEntry, bool) {
+	for _, e := range t.bucket(key) {
+		if e.key == key && key != 0 {
+			return e, true
+		}
+	}
+	return ttEntry{}, false
+}
+
+// store records a result for key. It replaces the first slot when that slot
+// holds the same position, a shallower result or one from an earlier
+// search, and the second slot otherwise.
+func (t *TranspositionTable) store(key uint64, depth int, value float64, b bound, move packedMove) {
+	slots := t.bucket(key)
+	slot := &slots[1]
+	if first := &slots[0]; first.key == key || first.age != t.age || depth >= int(first.depth) {
+		slot = first
+	}
+	*slot = ttEntry{key: key, value: value, move: move, depth: int8(depth), bound: b, age: t.age}
+}
+
+// packedMove stores a move in 16 bits: the from and to square indexes, the
+// promotion kind and whether it is set.
+type packedMove uint16
+
+// noMove is the packed zero move, which no legal move packs to.
+const noMove packedMove = 0
+
+func packMove(m engine.Move) packedMove {
+	p := packedMove(m.From.File*8+m.From.Rank) | packedMove(m.To.File*8+m.To.Rank)<<6 | packedMove(m.Promotion)<<12
+	if m.PromotionSet {
+		p |= 1 << 15
+	}
+	return p
+}
+
+// chanceKey is the table key of the chance node that follows move in the
+// position with the given hash.
+func chanceKey(hash uint64, move engine.Move) uint64 {
+	return hash ^ (uint64(packMove(move))+1)*0x9e3779b97f4a7c15
+}
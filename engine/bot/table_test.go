@@ -0,0 +1,54 @@
+package bot
+
+import "testing"
+
+func TestTranspositionTableKeepsDeepEntries(t *testing.T) {
+	table := NewTranspositionTable(8) // four buckets of two slots
+	// keys 1, 5 and 9 share a bucket
+	table.store(1, 5, 1.5, boundExact, noMove)
+	table.store(5, 2, 2.5, boundLower, noMove)
+	table.store(9, 3, 3.5, boundUpper, noMove)
+
+	if e, ok := table.probe(1); !ok || e.depth != 5 || e.value != 1.5 {
+		t.Fatalf("expected the deep entry to survive, got %+v (found %v)", e, ok)
+	}
+	if _, ok := table.probe(5); ok {
+		t.Fatalf("expected the shallow entry to be replaced")
+	}
+	if e, ok := table.probe(9); !ok || e.bound != boundUpper {
+		t.Fatalf("expected the newest entry, got %+v (found %v)", e, ok)
+	}
+
+	// entries of an earlier search give way to new ones
+	table.newSearch()
+	table.store(5, 1, 4.5, boundExact, noMove)
+	if _, ok := table.probe(1); ok {
+		t.Fatalf("expected the old deep entry to be replaced")
+	}
+	if e, ok := table.probe(5); !ok || e.value != 4.5 {
+		t.Fatalf("expected the new entry, got %+v (found %v)", e, ok)
+	}
+
+	table.Clear()
+	if _, ok := table.probe(9); ok {
+		t.Fatalf("expected Clear to empty the table")
+	}
+}
+
+func TestPackMoveIsUnique(t *testing.T) {
+	seen := map[packedMove]bool{noMove: true}
+	for _, fen := range []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+	} {
+		s := mustParseFEN(t, fen)
+		sr := &searcher{s: s}
+		for _, move := range sr.orderedMoves(noMove) {
+			p := packMove(move)
+			if seen[p] {
+				t.Fatalf("%s: move %+v packs to a used value %d", fen, move, p)
+			}
+			seen[p] = true
+		}
+	}
+}
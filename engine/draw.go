@@ -0,0 +1,49 @@
+package engine
+
+// RepetitionCount reports how many times the current position has occurred,
+// counting the current occurrence.
+func RepetitionCount(state *GameState) int {
+	key := state.Hash()
+	count := 1
+	for _, k := range state.positions {
+		if k == key {
+			count++
+		}
+	}
+	return count
+}
+
+// IsThreefoldRepetition reports whether the current position has occurred at least three times.
+func IsThreefoldRepetition(state *GameState) bool {
+	return RepetitionCount(state) >= 3
+}
+
+// IsFiftyMoveDraw reports whether fifty moves by each side have passed without
+// a capture, a pawn move, or a swap that relocated a pawn.
+func IsFiftyMoveDraw(state *GameState) bool {
+	return state.HalfmoveClock >= 100
+}
+
+// IsInsufficientMaterial reports whether neither side can ever deliver mate.
+// Only bare kings with at most one knight or bishop on the board qualify. Two
+// bishops on same-colored squares do not, because a swap can move a bishop
+// onto the other square color.
+func IsInsufficientMaterial(state *GameState) bool {
+	minors := 0
+	for f := 0; f < 8; f++ {
+		for r := 0; r < 8; r++ {
+			p := state.Board.Squares[f][r]
+			if p == nil {
+				continue
+			}
+			switch p.Kind {
+			case King:
+			case Knight, Bishop:
+				minors++
+			default:
+				return false
+			}
+		}
+	}
+	return minors <= 1
+}
@@ -0,0 +1,127 @@
+package engine
+
+import "testing"
+
+func mustParseFEN(t *testing.T, fen string) *GameState {
+	t.Helper()
+	s, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q) returned error: %v", fen, err)
+	}
+	return s
+}
+
+func applyUnswapped(t *testing.T, s *GameState, moves ...string) {
+	t.Helper()
+	for _, text := range moves {
+		s.SuppressNextSwap = true
+		applyMoves(t, s, text)
+	}
+}
+
+func applyMoves(t *testing.T, s *GameState, moves ...string) {
+	t.Helper()
+	for _, text := range moves {
+		from, err := ParseSquare(text[:2])
+		if err != nil {
+			t.Fatalf("bad move %q: %v", text, err)
+		}
+		to, err := ParseSquare(text[2:4])
+		if err != nil {
+			t.Fatalf("bad move %q: %v", text, err)
+		}
+		if err := ApplyMove(s, Move{From: from, To: to}); err != nil {
+			t.Fatalf("ApplyMove(%s) returned error: %v", text, err)
+		}
+	}
+}
+
+func TestInsufficientMaterial(t *testing.T) {
+	cases := []struct {
+		fen  string
+		want bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - -", true},
+		{"4k3/8/8/8/8/8/8/2B1K3 w - -", true},
+		{"4k3/8/8/8/8/8/8/1N2K3 w - -", true},
+		// same-colored bishops are not a dead draw once swaps can move a bishop
+		{"2b1k3/8/8/8/8/8/8/2B1K3 w - -", false},
+		{"4k3/8/8/8/8/8/8/R3K3 w - -", false},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - -", false},
+	}
+	for _, tc := range cases {
+		if got := IsInsufficientMaterial(mustParseFEN(t, tc.fen)); got != tc.want {
+			t.Fatalf("IsInsufficientMaterial(%q) = %v, want %v", tc.fen, got, tc.want)
+		}
+	}
+
+	if got := GameOutcome(mustParseFEN(t, "4k3/8/8/8/8/8/8/4K3 w - -")); got != OutcomeInsufficientMaterial {
+		t.Fatalf("expected insufficient material outcome, got %s", got)
+	}
+}
+
+func TestFiftyMoveRule(t *testing.T) {
+	s := mustParseFEN(t, "4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+	if IsFiftyMoveDraw(s) {
+		t.Fatalf("did not expect fifty-move draw at 99 plies")
+	}
+	applyUnswapped(t, s, "a1a2")
+	if !IsFiftyMoveDraw(s) {
+		t.Fatalf("expected fifty-move draw at %d plies", s.HalfmoveClock)
+	}
+	if got := GameOutcome(s); got != OutcomeFiftyMoveRule || !got.IsDraw() {
+		t.Fatalf("expected fifty-move outcome, got %s", got)
+	}
+}
+
+func TestSwapThatRelocatesPawnResetsHalfmoveClock(t *testing.T) {
+	// The only swap candidate for the moving king is the pawn.
+	s := mustParseFEN(t, "7k/8/8/8/8/8/P7/4K3 w - - 40 30 - 1")
+	if err := ApplyMove(s, Move{From: Position{File: 4, Rank: 0}, To: Position{File: 3, Rank: 0}}); err != nil {
+		t.Fatalf("ApplyMove returned error: %v", err)
+	}
+	if p := s.Board.Squares[3][0]; p == nil || p.Kind != Pawn {
+		t.Fatalf("expected the pawn to be swapped onto d1, got %+v", p)
+	}
+	if s.HalfmoveClock != 0 {
+		t.Fatalf("expected pawn swap to reset the halfmove clock, got %d", s.HalfmoveClock)
+	}
+}
+
+func TestThreefoldRepetition(t *testing.T) {
+	// Lone kings have no swap candidates, so the shuffle is deterministic.
+	s := mustParseFEN(t, "4k3/8/8/8/8/8/8/4K3 w - -")
+	shuffle := []string{"e1d1", "e8d8", "d1e1", "d8e8"}
+
+	applyMoves(t, s, shuffle...)
+	if got := RepetitionCount(s); got != 2 {
+		t.Fatalf("expected start position twice, got %d", got)
+	}
+	if IsThreefoldRepetition(s) {
+		t.Fatalf("did not expect threefold repetition yet")
+	}
+
+	applyMoves(t, s, shuffle...)
+	if !IsThreefoldRepetition(s) {
+		t.Fatalf("expected threefold repetition after repeating the shuffle twice")
+	}
+}
+
+func TestRepetitionKeyIncludesSwapSuppression(t *testing.T) {
+	s := mustParseFEN(t, "4k3/8/8/8/8/8/8/4K3 w - -")
+	shuffle := []string{"e1d1", "e8d8", "d1e1", "d8e8"}
+	applyMoves(t, s, shuffle...)
+	applyMoves(t, s, shuffle...)
+
+	// Same placement, but the side to move may not swap: a different position.
+	s.SuppressNextSwap = true
+	if got := RepetitionCount(s); got != 1 {
+		t.Fatalf("expected suppressed-swap position to be new, got count %d", got)
+	}
+
+	clone := s.Clone()
+	clone.SuppressNextSwap = false
+	if !IsThreefoldRepetition(clone) {
+		t.Fatalf("expected clone to keep position history")
+	}
+}
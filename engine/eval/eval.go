@@ -0,0 +1,260 @@
+// Package eval scores Swapchess positions for bots and analysis. Besides the
+// classic material and piece-square terms it weighs what swaps can do to a
+// position: how much the outcome depends on which piece gets swapped, how
+// likely a swap is to drop the king onto an attacked square, what holding
+// the suppression window is worth, and where pawns can be swapped to the back
+// rank.
+package eval
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+// Term names one part of the evaluation.
+type Term int
+
+const (
+	// Material is the piece value balance, kings excluded.
+	Material Term = iota
+	// PieceSquares rewards pieces on good squares for their kind.
+	PieceSquares
+	// SwapVolatility penalizes positions whose piece-square score swings
+	// widely with the swap target: the standard deviation of the score change
+	// over every swap a move could trigger.
+	SwapVolatility
+	// KingExposure penalizes the chance that the next swap drops the king
+	// onto a square the opponent attacks.
+	KingExposure
+	// SuppressionWindow rewards the side to move when its move will not swap
+	// because it is replying to a check.
+	SuppressionWindow
+	// BackRankPawns is the expected gain or loss from swaps that put a pawn
+	// on a back rank: stuck on its last rank, promoted there under
+	// BackRankPawnsPromote, or sent back to its first rank.
+	BackRankPawns
+
+	// NumTerms is the number of terms.
+	NumTerms
+)
+
+func (t Term) String() string {
+	switch t {
+	case Material:
+		return "material"
+	case PieceSquares:
+		return "piece-squares"
+	case SwapVolatility:
+		return "swap-volatility"
+	case KingExposure:
+		return "king-exposure"
+	case SuppressionWindow:
+		return "suppression-window"
+	case BackRankPawns:
+		return "back-rank-pawns"
+	default:
+		return "unknown"
+	}
+}
+
+// Tuning constants of the swap terms, in centipawns.
+const (
+	// volatilityScale is the share of the standard deviation charged, in percent.
+	volatilityScale = 50
+	// kingExposurePenalty is charged in full when every swap exposes the king.
+	kingExposurePenalty = 300
+	// suppressionBonus is the value of a reply that cannot swap.
+	suppressionBonus = 30
+	// stuckPawnPenalty is the cost of a pawn swapped onto its last rank, where
+	// it can never move again.
+	stuckPawnPenalty = 60
+	// demotedPawnPenalty is the cost of a pawn swapped back to its first rank.
+	demotedPawnPenalty = 20
+)
+
+// Breakdown holds the score of each term in centipawns from the point of
+// view of the side to move.
+type Breakdown [NumTerms]int
+
+// Total is the sum of the terms.
+func (b Breakdown) Total() int {
+	total := 0
+	for _, v := range b {
+		total += v
+	}
+	return total
+}
+
+// Weighted is the sum of the terms scaled by weights, given in percent.
+func (b Breakdown) Weighted(weights Weights) int {
+	total := 0
+	for t, v := range b {
+		total += v * weights[t] / 100
+	}
+	return total
+}
+
+// String lists the terms and the total, e.g. "material 100, ..., total 112".
+func (b Breakdown) String() string {
+	var sb strings.Builder
+	for t, v := range b {
+		fmt.Fprintf(&sb, "%s %d, ", Term(t), v)
+	}
+	fmt.Fprintf(&sb, "total %d", b.Total())
+	return sb.String()
+}
+
+// Weights scales each term in percent, for tuning.
+type Weights [NumTerms]int
+
+// DefaultWeights counts every term once.
+var DefaultWeights = Weights{100, 100, 100, 100, 100, 100}
+
+// Evaluate returns the score of state in centipawns from the point of view
+// of the side to move. It does not change state.
+func Evaluate(state *engine.GameState) int {
+	return Explain(state).Total()
+}
+
+// Explain evaluates state like Evaluate and reports the score of each term.
+func Explain(state *engine.GameState) Breakdown {
+	rules := engine.ClassicRules()
+	if state.Rules != nil {
+		rules = *state.Rules
+	}
+
+	var b Breakdown
+	for _, color := range []engine.Color{engine.White, engine.Black} {
+		var side Breakdown
+		for f := 0; f < 8; f++ {
+			for r := 0; r < 8; r++ {
+				p := state.Board.Squares[f][r]
+				if p == nil || p.Color != color {
+					continue
+				}
+				side[Material] += pieceValues[p.Kind]
+				side[PieceSquares] += pieceSquare(p.Kind, color, engine.Position{File: f, Rank: r})
+			}
+		}
+		// the side to move does not swap while SuppressNextSwap is set
+		if rules.Swaps > 0 && rules.SwapChance > 0 && !(color == state.Turn && state.SuppressNextSwap) {
+			side[SwapVolatility], side[KingExposure], side[BackRankPawns] = swapTerms(state, rules, color)
+		}
+
+		if color != state.Turn {
+			for t := range side {
+				side[t] = -side[t]
+			}
+		}
+		for t := range b {
+			b[t] += side[t]
+		}
+	}
+
+	if state.SuppressNextSwap && rules.SuppressAfterCheck {
+		b[SuppressionWindow] = suppressionBonus
+	}
+	return b
+}
+
+// swapTerms scores the swaps color's next move could trigger. Each piece is
+// taken as equally likely to move and each piece CanSwap allows as equally
+// likely to be drawn; the scores are scaled by the chance that a swap happens.
+// Under the self-check policies other than SelfCheckAllow exposing swaps never
+// happen, so the king is not exposed; the other terms still count them.
+func swapTerms(state *engine.GameState, rules engine.Rules, color engine.Color) (volatility, exposure, backRank int) {
+	var (
+		pieces [16]engine.Position
+		kinds  [16]engine.PieceKind
+		own    [16]int // piece-square bonus on the current square
+	)
+	count, king := 0, -1
+	for f := 0; f < 8 && count < len(pieces); f++ {
+		for r := 0; r < 8 && count < len(pieces); r++ {
+			if p := state.Board.Squares[f][r]; p != nil && p.Color == color {
+				if p.Kind == engine.King {
+					king = count
+				}
+				pieces[count] = engine.Position{File: f, Rank: r}
+				kinds[count] = p.Kind
+				own[count] = pieceSquare(p.Kind, color, pieces[count])
+				count++
+			}
+		}
+	}
+	if rules.SelfCheckSwaps != engine.SelfCheckAllow {
+		king = -1
+	}
+	opponent := engine.Black
+	if color == engine.Black {
+		opponent = engine.White
+	}
+	// attacked caches whether the opponent attacks each piece's square, for
+	// the squares the king can be swapped onto.
+	var attacked [16]bool
+	if king >= 0 {
+		for i, pos := range pieces[:count] {
+			attacked[i] = engine.SquareAttacked(state, pos, opponent)
+		}
+	}
+
+	// n, sum and sumSq give the variance of the piece-square changes.
+	n, sum, sumSq := 0, 0, 0
+	// exposed and pawnRisk add up each piece's share in millicentipawns, in
+	// integers so that the result does not depend on the board scan order.
+	exposed, pawnRisk := 0, 0
+	for i, from := range pieces[:count] {
+		swaps, hits, landings := 0, 0, 0
+		for j, to := range pieces[:count] {
+			if !engine.CanSwap(state, from, to) {
+				continue
+			}
+			swaps++
+			d := pieceSquare(kinds[i], color, to) + pieceSquare(kinds[j], color, from) - own[i] - own[j]
+			sum += d
+			sumSq += d * d
+			landings += pawnLanding(kinds[i], color, rules, to) + pawnLanding(kinds[j], color, rules, from)
+			if (i == king && attacked[j]) || (j == king && attacked[i]) {
+				hits++
+			}
+		}
+		if swaps > 0 {
+			n += swaps
+			exposed += hits * kingExposurePenalty * 1000 / (count * swaps)
+			pawnRisk += landings * 1000 / (count * swaps)
+		}
+	}
+
+	if n > 0 {
+		mean := float64(sum) / float64(n)
+		variance := math.Max(float64(sumSq)/float64(n)-mean*mean, 0)
+		volatility = -int(math.Round(rules.SwapChance * math.Sqrt(variance) * volatilityScale / 100))
+	}
+	exposure = -int(math.Round(rules.SwapChance * float64(exposed) / 1000))
+	backRank = int(math.Round(rules.SwapChance * float64(pawnRisk) / 1000))
+	return volatility, exposure, backRank
+}
+
+// pawnLanding scores a piece of kind and color arriving on to by a swap when
+// it is a pawn reaching a back rank.
+func pawnLanding(kind engine.PieceKind, color engine.Color, rules engine.Rules, to engine.Position) int {
+	if kind != engine.Pawn {
+		return 0
+	}
+	last, first := 7, 0
+	if color == engine.Black {
+		last, first = 0, 7
+	}
+	switch {
+	case to.Rank == last && rules.BackRankPawns == engine.BackRankPawnsPromote:
+		return pieceValues[engine.Queen] - pieceValues[engine.Pawn]
+	case to.Rank == last:
+		return -stuckPawnPenalty
+	case to.Rank == first:
+		return -demotedPawnPenalty
+	}
+	return 0
+}
@@ -0,0 +1,184 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+func mustParseFEN(t *testing.T, fen string) *engine.GameState {
+	t.Helper()
+	s, err := engine.ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q) returned error: %v", fen, err)
+	}
+	return s
+}
+
+// mirror flips a plain FEN top to bottom and swaps the colors.
+func mirror(fen string) string {
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	swapCase := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			case r >= 'A' && r <= 'Z':
+				return r - 'A' + 'a'
+			}
+			return r
+		}, s)
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	fields[2] = swapCase(fields[2])
+	return strings.Join(fields, " ")
+}
+
+func TestStartPositionIsBalanced(t *testing.T) {
+	b := Explain(engine.NewGame())
+	if b != (Breakdown{}) {
+		t.Fatalf("expected every term to cancel out, got %v", b)
+	}
+}
+
+func TestEvaluateIsColorSymmetric(t *testing.T) {
+	for _, fen := range []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkb1r/pppp1ppp/5n2/4p3/2B1P3/8/PPPP1PPP/RNBQK1NR w KQkq - 2 3",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	} {
+		got, want := Explain(mustParseFEN(t, mirror(fen))), Explain(mustParseFEN(t, fen))
+		if got != want {
+			t.Fatalf("%s: mirrored position scores %v, expected %v", fen, got, want)
+		}
+	}
+}
+
+func TestScoresFollowTheSideToMove(t *testing.T) {
+	white := Explain(mustParseFEN(t, "4k3/8/8/8/8/8/8/3QK3 w - - 0 1"))
+	black := Explain(mustParseFEN(t, "4k3/8/8/8/8/8/8/3QK3 b - - 0 1"))
+	if white[Material] != 900 || black[Material] != -900 {
+		t.Fatalf("expected material +900 and -900, got %d and %d", white[Material], black[Material])
+	}
+	if white.Total() != -black.Total() {
+		t.Fatalf("expected opposite totals, got %d and %d", white.Total(), black.Total())
+	}
+}
+
+func TestKingExposure(t *testing.T) {
+	// Every white swap puts the king on a1, which the bishop on b2 attacks.
+	s := mustParseFEN(t, "4k3/8/8/8/8/8/1b6/R3K3 w - - 0 1")
+	if got := Explain(s)[KingExposure]; got != -kingExposurePenalty {
+		t.Fatalf("expected king exposure %d, got %d", -kingExposurePenalty, got)
+	}
+
+	safe, err := engine.RulesPreset("safe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Rules = &safe
+	if got := Explain(s)[KingExposure]; got != 0 {
+		t.Fatalf("expected no king exposure when kings do not swap, got %d", got)
+	}
+}
+
+func TestSuppressionWindow(t *testing.T) {
+	s := mustParseFEN(t, "4k3/8/8/8/8/8/4R3/4K3 b - - 0 1 s 1")
+	if got := Explain(s)[SuppressionWindow]; got != suppressionBonus {
+		t.Fatalf("expected suppression bonus %d, got %d", suppressionBonus, got)
+	}
+	s.SuppressNextSwap = false
+	if got := Explain(s)[SuppressionWindow]; got != 0 {
+		t.Fatalf("expected no suppression bonus, got %d", got)
+	}
+}
+
+func TestSuppressedSideHasNoSwapTerms(t *testing.T) {
+	// as in TestKingExposure, but white's next move cannot swap
+	s := mustParseFEN(t, "4k3/8/8/8/8/8/1b6/R3K3 w - - 0 1 s 1")
+	if b := Explain(s); b[SwapVolatility] != 0 || b[KingExposure] != 0 || b[BackRankPawns] != 0 {
+		t.Fatalf("expected no swap terms for a suppressed swap, got %v", b)
+	}
+	s.SuppressNextSwap = false
+	if got := Explain(s)[KingExposure]; got != -kingExposurePenalty {
+		t.Fatalf("expected king exposure %d once the swap can happen, got %d", -kingExposurePenalty, got)
+	}
+}
+
+func TestBackRankPawnsFollowThePolicy(t *testing.T) {
+	// The pawn on b5 can swap with the rook on h8, its last rank.
+	s := mustParseFEN(t, "k6R/8/8/1P6/3K4/8/8/8 w - - 0 1")
+	rules := engine.ClassicRules()
+	s.Rules = &rules
+	for _, tc := range []struct {
+		policy engine.BackRankPawnPolicy
+		check  func(int) bool
+	}{
+		{engine.BackRankPawnsAllow, func(v int) bool { return v < 0 }},
+		{engine.BackRankPawnsExclude, func(v int) bool { return v == 0 }},
+		{engine.BackRankPawnsPromote, func(v int) bool { return v > 0 }},
+	} {
+		rules.BackRankPawns = tc.policy
+		if got := Explain(s)[BackRankPawns]; !tc.check(got) {
+			t.Fatalf("%v: unexpected back-rank pawn score %d", tc.policy, got)
+		}
+	}
+}
+
+func TestSwapTermsVanishWithoutSwaps(t *testing.T) {
+	s := mustParseFEN(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if b := Explain(s); b[SwapVolatility] == 0 {
+		t.Fatalf("expected a volatile middlegame, got %v", b)
+	}
+	rules := engine.ClassicRules()
+	rules.Swaps = 0
+	s.Rules = &rules
+	b := Explain(s)
+	if b[SwapVolatility] != 0 || b[KingExposure] != 0 || b[BackRankPawns] != 0 {
+		t.Fatalf("expected no swap terms without swaps, got %v", b)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	b := Breakdown{Material: 300, PieceSquares: 40, SwapVolatility: -20}
+	if got := b.Weighted(DefaultWeights); got != b.Total() {
+		t.Fatalf("expected default weights to give the total %d, got %d", b.Total(), got)
+	}
+	if got := b.Weighted(Weights{Material: 100, SwapVolatility: 200}); got != 260 {
+		t.Fatalf("expected weighted score 260, got %d", got)
+	}
+}
+
+func TestExplainLeavesStateUntouched(t *testing.T) {
+	s := mustParseFEN(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	safe, err := engine.RulesPreset("safe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Rules = &safe
+	before, hash := s.FEN(), s.Hash()
+	Explain(s)
+	if s.FEN() != before || s.Hash() != hash {
+		t.Fatalf("Explain changed the state to %s", s.FEN())
+	}
+}
+
+func BenchmarkExplain(b *testing.B) {
+	s, err := engine.ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		Explain(s)
+	}
+}
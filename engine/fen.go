@@ -0,0 +1,274 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StartFEN is the SwapFEN of the position returned by NewGame.
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 - 1"
+
+var (
+	ErrInvalidFEN = errors.New("invalid fen")
+)
+
+// ParseFEN builds a GameState from a SwapFEN or standard FEN string.
+//
+// SwapFEN extends standard FEN with two trailing Swapchess fields:
+//
+//	<placement> <side> <castling> <en passant> <halfmove> <fullmove> <suppress> <seed>
+//
+// The suppress field is "s" when SuppressNextSwap is set and "-" otherwise.
+// The seed field is the decimal RandSeed. Plain FEN is accepted as well:
+// missing counters default to 0 and 1, missing swap fields to "-" and seed 1.
+// The parsed state uses CounterSource with Ply derived from the move counters.
+func ParseFEN(fen string) (*GameState, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 || len(fields) > 8 {
+		return nil, fmt.Errorf("%w: expected 4 to 8 fields, got %d", ErrInvalidFEN, len(fields))
+	}
+
+	state := &GameState{RandSeed: 1, FullmoveNumber: 1, SwapSource: CounterSource{}, positions: make([]uint64, 0, historyCapacity)}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("%w: expected 8 ranks, got %d", ErrInvalidFEN, len(ranks))
+	}
+	for i, row := range ranks {
+		rank := 7 - i
+		file := 0
+		for _, c := range row {
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				if file > 8 {
+					return nil, fmt.Errorf("%w: rank %d overflows", ErrInvalidFEN, rank+1)
+				}
+				continue
+			}
+			piece, ok := pieceFromFEN(c)
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown piece %q", ErrInvalidFEN, c)
+			}
+			if file > 7 {
+				return nil, fmt.Errorf("%w: rank %d overflows", ErrInvalidFEN, rank+1)
+			}
+			state.Board.Squares[file][rank] = piece
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("%w: rank %d has %d files", ErrInvalidFEN, rank+1, file)
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		state.Turn = White
+	case "b":
+		state.Turn = Black
+	default:
+		return nil, fmt.Errorf("%w: unknown side to move %q", ErrInvalidFEN, fields[1])
+	}
+
+	if fields[2] != "-" {
+		for _, c := range fields[2] {
+			switch c {
+			case 'K':
+				state.WhiteCanCastleKingSide = true
+			case 'Q':
+				state.WhiteCanCastleQueenSide = true
+			case 'k':
+				state.BlackCanCastleKingSide = true
+			case 'q':
+				state.BlackCanCastleQueenSide = true
+			default:
+				return nil, fmt.Errorf("%w: unknown castling right %q", ErrInvalidFEN, c)
+			}
+		}
+	}
+
+	if fields[3] != "-" {
+		pos, err := ParseSquare(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%w: en passant: %v", ErrInvalidFEN, err)
+		}
+		// ranks 2 and 7 are reachable by a double move from a back-rank pawn
+		if pos.Rank == 0 || pos.Rank == 3 || pos.Rank == 4 || pos.Rank == 7 {
+			return nil, fmt.Errorf("%w: en passant square %s is not on rank 2, 3, 6 or 7", ErrInvalidFEN, fields[3])
+		}
+		state.HasEnPassant = true
+		state.EnPassant = pos
+	}
+
+	if len(fields) > 4 {
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: bad halfmove clock %q", ErrInvalidFEN, fields[4])
+		}
+		state.HalfmoveClock = n
+	}
+	if len(fields) > 5 {
+		n, err := strconv.Atoi(fields[5])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: bad fullmove number %q", ErrInvalidFEN, fields[5])
+		}
+		state.FullmoveNumber = n
+	}
+	if len(fields) > 6 {
+		switch fields[6] {
+		case "s":
+			state.SuppressNextSwap = true
+		case "-":
+		default:
+			return nil, fmt.Errorf("%w: bad swap suppression field %q", ErrInvalidFEN, fields[6])
+		}
+	}
+	if len(fields) > 7 {
+		seed, err := strconv.ParseInt(fields[7], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad seed %q", ErrInvalidFEN, fields[7])
+		}
+		state.RandSeed = seed
+	}
+
+	state.Ply = (state.FullmoveNumber - 1) * 2
+	if state.Turn == Black {
+		state.Ply++
+	}
+	state.Rehash()
+	return state, nil
+}
+
+// FEN returns the SwapFEN of the state.
+func (g *GameState) FEN() string {
+	fullmove := g.FullmoveNumber
+	if fullmove < 1 {
+		fullmove = 1
+	}
+	suppress := "-"
+	if g.SuppressNextSwap {
+		suppress = "s"
+	}
+	return fmt.Sprintf("%s %d %d %s %d", g.positionFEN(), g.HalfmoveClock, fullmove, suppress, g.RandSeed)
+}
+
+// positionFEN returns the placement, side, castling and en passant FEN fields.
+func (g *GameState) positionFEN() string {
+	var b strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			p := g.Board.Squares[file][rank]
+			if p == nil {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			b.WriteByte(pieceToFEN(p))
+		}
+		if empty > 0 {
+			b.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			b.WriteByte('/')
+		}
+	}
+
+	if g.Turn == White {
+		b.WriteString(" w ")
+	} else {
+		b.WriteString(" b ")
+	}
+
+	castling := ""
+	if g.WhiteCanCastleKingSide {
+		castling += "K"
+	}
+	if g.WhiteCanCastleQueenSide {
+		castling += "Q"
+	}
+	if g.BlackCanCastleKingSide {
+		castling += "k"
+	}
+	if g.BlackCanCastleQueenSide {
+		castling += "q"
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	b.WriteString(castling)
+
+	if g.HasEnPassant {
+		b.WriteString(" " + SquareString(g.EnPassant))
+	} else {
+		b.WriteString(" -")
+	}
+	return b.String()
+}
+
+// ParseSquare parses algebraic square notation such as "e4".
+func ParseSquare(s string) (Position, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return Position{}, fmt.Errorf("bad square %q", s)
+	}
+	return Position{File: int(s[0] - 'a'), Rank: int(s[1] - '1')}, nil
+}
+
+// SquareString formats a position in algebraic notation such as "e4".
+func SquareString(pos Position) string {
+	return string([]byte{byte('a' + pos.File), byte('1' + pos.Rank)})
+}
+
+func pieceFromFEN(c rune) (*Piece, bool) {
+	color := White
+	if c >= 'a' && c <= 'z' {
+		color = Black
+		c -= 'a' - 'A'
+	}
+	var kind PieceKind
+	switch c {
+	case 'P':
+		kind = Pawn
+	case 'N':
+		kind = Knight
+	case 'B':
+		kind = Bishop
+	case 'R':
+		kind = Rook
+	case 'Q':
+		kind = Queen
+	case 'K':
+		kind = King
+	default:
+		return nil, false
+	}
+	return &Piece{Kind: kind, Color: color}, true
+}
+
+func pieceToFEN(p *Piece) byte {
+	var c byte
+	switch p.Kind {
+	case Pawn:
+		c = 'P'
+	case Knight:
+		c = 'N'
+	case Bishop:
+		c = 'B'
+	case Rook:
+		c = 'R'
+	case Queen:
+		c = 'Q'
+	case King:
+		c = 'K'
+	default:
+		c = '?'
+	}
+	if p.Color == Black {
+		c += 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewGameFENMatchesStartFEN(t *testing.T) {
+	if got := NewGame().FEN(); got != StartFEN {
+		t.Fatalf("expected start FEN %q, got %q", StartFEN, got)
+	}
+}
+
+func TestParseFENRoundTripsSwapFields(t *testing.T) {
+	fen := "r3k2r/8/8/3pP3/8/8/8/R3K2R w Kq d6 7 23 s -42"
+	s, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN returned error: %v", err)
+	}
+
+	if s.Turn != White {
+		t.Fatalf("expected white to move")
+	}
+	if !s.WhiteCanCastleKingSide || s.WhiteCanCastleQueenSide || s.BlackCanCastleKingSide || !s.BlackCanCastleQueenSide {
+		t.Fatalf("unexpected castling rights: %+v", s)
+	}
+	if !s.HasEnPassant || s.EnPassant != (Position{File: 3, Rank: 5}) {
+		t.Fatalf("expected en passant target d6, got %v %+v", s.HasEnPassant, s.EnPassant)
+	}
+	if s.HalfmoveClock != 7 || s.FullmoveNumber != 23 {
+		t.Fatalf("unexpected counters: halfmove %d fullmove %d", s.HalfmoveClock, s.FullmoveNumber)
+	}
+	if !s.SuppressNextSwap {
+		t.Fatalf("expected SuppressNextSwap from the suppress field")
+	}
+	if s.RandSeed != -42 {
+		t.Fatalf("expected RandSeed -42, got %d", s.RandSeed)
+	}
+	if p := s.Board.Squares[4][4]; p == nil || p.Kind != Pawn || p.Color != White {
+		t.Fatalf("expected white pawn on e5, got %+v", p)
+	}
+	if p := s.Board.Squares[7][7]; p == nil || p.Kind != Rook || p.Color != Black {
+		t.Fatalf("expected black rook on h8, got %+v", p)
+	}
+
+	if got := s.FEN(); got != fen {
+		t.Fatalf("expected round trip %q, got %q", fen, got)
+	}
+}
+
+func TestParseFENAcceptsPlainFEN(t *testing.T) {
+	s, err := ParseFEN("8/8/8/8/8/8/8/K6k b - -")
+	if err != nil {
+		t.Fatalf("ParseFEN returned error: %v", err)
+	}
+	if s.Turn != Black || s.HalfmoveClock != 0 || s.FullmoveNumber != 1 || s.SuppressNextSwap || s.RandSeed != 1 {
+		t.Fatalf("expected plain FEN defaults, got %+v", s)
+	}
+	if got := s.FEN(); got != "8/8/8/8/8/8/8/K6k b - - 0 1 - 1" {
+		t.Fatalf("unexpected FEN output %q", got)
+	}
+}
+
+func TestParseFENRejectsMalformedInput(t *testing.T) {
+	for _, fen := range []string{
+		"",
+		"8/8/8/8/8/8/8 w - -",
+		"9/8/8/8/8/8/8/8 w - -",
+		"8/8/8/8/8/8/8/7x w - -",
+		"8/8/8/8/8/8/8/8 x - -",
+		"8/8/8/8/8/8/8/8 w X -",
+		"8/8/8/8/8/8/8/8 w - e4",
+		"8/8/8/8/8/8/8/8 w - - -1 1",
+		"8/8/8/8/8/8/8/8 w - - 0 0",
+		"8/8/8/8/8/8/8/8 w - - 0 1 x",
+		"8/8/8/8/8/8/8/8 w - - 0 1 - seed",
+		"8/8/8/8/8/8/8/8 w - - 0 1 - 1 extra",
+	} {
+		if _, err := ParseFEN(fen); !errors.Is(err, ErrInvalidFEN) {
+			t.Fatalf("expected ErrInvalidFEN for %q, got %v", fen, err)
+		}
+	}
+}
+
+func TestApplyMoveUpdatesMoveCounters(t *testing.T) {
+	s, err := ParseFEN("4k3/8/8/8/8/8/4P3/4K1N1 w - - 5 9 s 1")
+	if err != nil {
+		t.Fatalf("ParseFEN returned error: %v", err)
+	}
+
+	// White knight move with the swap suppressed: clock advances, fullmove does not.
+	if err := ApplyMove(s, Move{From: Position{File: 6, Rank: 0}, To: Position{File: 5, Rank: 2}}); err != nil {
+		t.Fatalf("ApplyMove returned error: %v", err)
+	}
+	if s.HalfmoveClock != 6 || s.FullmoveNumber != 9 {
+		t.Fatalf("after white move: halfmove %d fullmove %d", s.HalfmoveClock, s.FullmoveNumber)
+	}
+
+	s.SuppressNextSwap = true
+	if err := ApplyMove(s, Move{From: Position{File: 4, Rank: 7}, To: Position{File: 3, Rank: 7}}); err != nil {
+		t.Fatalf("ApplyMove returned error: %v", err)
+	}
+	if s.HalfmoveClock != 7 || s.FullmoveNumber != 10 {
+		t.Fatalf("after black move: halfmove %d fullmove %d", s.HalfmoveClock, s.FullmoveNumber)
+	}
+
+	s.SuppressNextSwap = true
+	if err := ApplyMove(s, Move{From: Position{File: 4, Rank: 1}, To: Position{File: 4, Rank: 3}}); err != nil {
+		t.Fatalf("ApplyMove returned error: %v", err)
+	}
+	if s.HalfmoveClock != 0 {
+		t.Fatalf("expected pawn move to reset halfmove clock, got %d", s.HalfmoveClock)
+	}
+}
@@ -1,5 +1,9 @@
 package engine
 
+// historyCapacity is the room for repetition history that new and cloned
+// states start with, so that searches rarely grow it while making moves.
+const historyCapacity = 64
+
 // NewGame returns a GameState set to a standard chess starting position.
 func NewGame() *GameState {
 	gs := &GameState{
@@ -10,6 +14,9 @@ func NewGame() *GameState {
 		WhiteCanCastleQueenSide: true,
 		BlackCanCastleKingSide:  true,
 		BlackCanCastleQueenSide: true,
+		FullmoveNumber:          1,
+		SwapSource:              CounterSource{},
+		positions:               make([]uint64, 0, historyCapacity),
 	}
 
 	// Place white pieces
@@ -37,6 +44,7 @@ func NewGame() *GameState {
 	for f := 0; f < 8; f++ {
 		gs.Board.Squares[f][6] = &Piece{Kind: Pawn, Color: Black}
 	}
+	gs.Rehash()
 
 	return gs
 }
@@ -53,6 +61,17 @@ func (g *GameState) Clone() *GameState {
 		WhiteCanCastleQueenSide: g.WhiteCanCastleQueenSide,
 		BlackCanCastleKingSide:  g.BlackCanCastleKingSide,
 		BlackCanCastleQueenSide: g.BlackCanCastleQueenSide,
+		HalfmoveClock:           g.HalfmoveClock,
+		FullmoveNumber:          g.FullmoveNumber,
+		Ply:                     g.Ply,
+		SwapSource:              g.SwapSource,
+		// UnmakeMove truncates positions in place, so the clone needs its own copy.
+		positions: append(make([]uint64, 0, len(g.positions)+historyCapacity), g.positions...),
+	}
+
+	if g.Rules != nil {
+		rules := *g.Rules
+		ng.Rules = &rules
 	}
 
 	for f := 0; f < 8; f++ {
@@ -64,6 +83,7 @@ func (g *GameState) Clone() *GameState {
 			}
 		}
 	}
+	ng.Rehash()
 
 	return ng
 }
@@ -15,3 +15,7 @@ type Move struct {
 func (m Move) HasExplicitPromotion() bool {
 	return m.PromotionSet || m.Promotion != Pawn
 }
+
+func moveText(m Move) string {
+	return SquareString(m.From) + SquareString(m.To)
+}
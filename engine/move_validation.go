@@ -1,5 +1,117 @@
 package engine
 
+// legality holds what the king-safety test of every move in a position
+// shares. It tests moves on the bitboards without playing them, so it never
+// changes the state.
+type legality struct {
+	state    *GameState
+	b        *bitboards
+	us, them Color
+	own, all uint64
+	// king is the square of the mover's king, or -1 without one.
+	king int
+	// exposed holds the squares whose pieces may expose the king by moving.
+	// It starts out as every square; findPins narrows it to the pinned pieces.
+	exposed uint64
+}
+
+func newLegality(state *GameState) legality {
+	b := &state.bitboards
+	l := legality{state: state, b: b, us: state.Turn, them: opposite(state.Turn), own: b.occupied[state.Turn], all: b.all(), king: -1, exposed: ^uint64(0)}
+	if kings := b.pieces[l.us][King]; kings != 0 {
+		l.king = lowestSquare(kings)
+	}
+	return l
+}
+
+// findPins narrows exposed to the pieces pinned against the king, unless the
+// king is attacked. It pays off when many moves are tested, as in LegalMoves.
+func (l *legality) findPins() {
+	if l.king < 0 || l.b.attackers(l.king, l.them, l.all) != 0 {
+		return
+	}
+	p := &l.b.pieces[l.them]
+	snipers := rookAttacks(l.king, 0)&(p[Rook]|p[Queen]) | bishopAttacks(l.king, 0)&(p[Bishop]|p[Queen])
+	l.exposed = 0
+	for ; snipers != 0; snipers &= snipers - 1 {
+		if blockers := between(l.king, lowestSquare(snipers)) & l.all; blockers&(blockers-1) == 0 {
+			l.exposed |= blockers
+		}
+	}
+}
+
+// free reports whether the piece on from, which must not be the king, may
+// move anywhere without exposing the king.
+func (l *legality) free(from int) bool {
+	return l.king >= 0 && l.exposed&(1<<from) == 0
+}
+
+// keepsKingSafe reports whether moving the piece on from to to, which must not
+// be the king, leaves the mover's king unattacked. victim holds the square of
+// a pawn taken en passant, if any.
+func (l *legality) keepsKingSafe(from, to int, victim uint64) bool {
+	if l.king < 0 {
+		// the game already ended with the capture of the mover's king
+		return false
+	}
+	toBit := uint64(1) << to
+	if l.b.pieces[l.them][King]&toBit != 0 {
+		// capturing the king ends the game, so the mover's own safety no longer matters
+		return true
+	}
+	if victim == 0 && l.exposed&(1<<from) == 0 {
+		return true
+	}
+	occ := l.all&^(1<<from)&^victim | toBit
+	return l.b.attackers(l.king, l.them, occ)&^(toBit|victim) == 0
+}
+
+// kingStepSafe reports whether the king on from may step to the neighboring square to.
+func (l *legality) kingStepSafe(from, to int) bool {
+	if l.king < 0 {
+		return false
+	}
+	toBit := uint64(1) << to
+	if l.b.pieces[l.them][King]&toBit != 0 {
+		return true
+	}
+	occ := l.all&^(1<<from) | toBit
+	return l.b.attackers(to, l.them, occ)&^toBit == 0
+}
+
+// castles checks a two-square king move: the king and rook must stand on
+// their home squares with the right intact, the squares between them must be
+// empty, and the king may not start, pass or end on an attacked square.
+func (l *legality) castles(move Move) bool {
+	rank := 0
+	if l.us == Black {
+		rank = 7
+	}
+	if move.From != (Position{File: 4, Rank: rank}) {
+		return false
+	}
+	side := CastleKingSide
+	if move.To.File < move.From.File {
+		side = CastleQueenSide
+	}
+	if !hasCastlingRight(l.state, l.us, side) {
+		return false
+	}
+	rookFrom, rookTo := castlingRookSquares(rank, side)
+	from, to, rf, rt := squareIndex(move.From), squareIndex(move.To), squareIndex(rookFrom), squareIndex(rookTo)
+	if l.b.pieces[l.us][Rook]&(1<<rf) == 0 || between(from, rf)&l.all != 0 {
+		return false
+	}
+	for _, sq := range [3]int{from, rt, to} {
+		if l.b.attackers(sq, l.them, l.all) != 0 {
+			return false
+		}
+	}
+	// the rook leaves its corner, which might uncover the king's new square
+	occ := l.all&^(1<<from)&^(1<<rf) | 1<<to | 1<<rt
+	return l.b.attackers(to, l.them, occ) == 0
+}
+
 func isLegalMove(state *GameState, move Move) bool {
 	// bounds
 	if move.From.File < 0 || move.From.File > 7 || move.From.Rank < 0 || move.From.Rank > 7 {
@@ -22,39 +134,6 @@ func isLegalMove(state *GameState, move Move) bool {
 		return false // cannot capture own piece
 	}
 
-	abs := func(x int) int {
-		if x < 0 {
-			return -x
-		}
-		return x
-	}
-	sign := func(x int) int {
-		if x < 0 {
-			return -1
-		}
-		if x > 0 {
-			return 1
-		}
-		return 0
-	}
-
-	pathClearBetween := func(a, b Position) bool {
-		df := b.File - a.File
-		dr := b.Rank - a.Rank
-		sF := sign(df)
-		sR := sign(dr)
-		f := a.File + sF
-		r := a.Rank + sR
-		for f != b.File || r != b.Rank {
-			if state.Board.Squares[f][r] != nil {
-				return false
-			}
-			f += sF
-			r += sR
-		}
-		return true
-	}
-
 	df := move.To.File - move.From.File
 	dr := move.To.Rank - move.From.Rank
 	adf := abs(df)
@@ -68,179 +147,48 @@ func isLegalMove(state *GameState, move Move) bool {
 		}
 	}
 
+	l := newLegality(state)
+	from, to := squareIndex(move.From), squareIndex(move.To)
+	toBit := uint64(1) << to
+
 	switch piece.Kind {
 	case Pawn:
-		if piece.Color == White {
-			// forward one to empty
-			if df == 0 && dr == 1 && dest == nil {
-				return !wouldLeaveKingInCheck(state, move)
-			}
-			// initial double move from rank 1 to rank 3
-			if df == 0 && dr == 2 && move.From.Rank == 1 && dest == nil {
-				// ensure intermediate square is clear
-				mid := Position{File: move.From.File, Rank: move.From.Rank + 1}
-				if state.Board.Squares[mid.File][mid.Rank] == nil {
-					return !wouldLeaveKingInCheck(state, move)
-				}
-			}
-			// captures (including en passant)
-			if adf == 1 && dr == 1 {
-				if dest != nil && dest.Color == Black {
-					return !wouldLeaveKingInCheck(state, move)
-				}
-				if dest == nil && state.HasEnPassant && move.To == state.EnPassant {
-					return !wouldLeaveKingInCheck(state, move)
-				}
-			}
-		} else {
-			if df == 0 && dr == -1 && dest == nil {
-				return !wouldLeaveKingInCheck(state, move)
-			}
-			// initial double move for black from rank 6 to rank 4
-			if df == 0 && dr == -2 && move.From.Rank == 6 && dest == nil {
-				mid := Position{File: move.From.File, Rank: move.From.Rank - 1}
-				if state.Board.Squares[mid.File][mid.Rank] == nil {
-					return !wouldLeaveKingInCheck(state, move)
-				}
-			}
-			if adf == 1 && dr == -1 {
-				if dest != nil && dest.Color == White {
-					return !wouldLeaveKingInCheck(state, move)
-				}
-				if dest == nil && state.HasEnPassant && move.To == state.EnPassant {
-					return !wouldLeaveKingInCheck(state, move)
-				}
+		dir, firstRanks := 1, move.From.Rank <= 1
+		if piece.Color == Black {
+			// black double moves from the seventh or eighth rank
+			dir, firstRanks = -1, move.From.Rank >= 6
+		}
+		switch {
+		case df == 0 && dr == dir && dest == nil:
+			return l.keepsKingSafe(from, to, 0)
+		case df == 0 && dr == 2*dir && firstRanks && dest == nil:
+			// a swap may have put the pawn on its own back rank; the square
+			// passed over must be empty too
+			if state.Board.Squares[move.From.File][move.From.Rank+dir] == nil {
+				return l.keepsKingSafe(from, to, 0)
 			}
+		case adf == 1 && dr == dir && dest != nil:
+			return l.keepsKingSafe(from, to, 0)
+		case adf == 1 && dr == dir && isEnPassantTarget(state, move):
+			return l.keepsKingSafe(from, to, squareBit(Position{File: move.To.File, Rank: move.From.Rank}))
 		}
 		return false
 	case Knight:
-		if (adf == 2 && adr == 1) || (adf == 1 && adr == 2) {
-			return !wouldLeaveKingInCheck(state, move)
-		}
-		return false
+		return knightAttacks[from]&toBit != 0 && l.keepsKingSafe(from, to, 0)
 	case Bishop:
-		if adf == adr && adf > 0 && pathClearBetween(move.From, move.To) {
-			return !wouldLeaveKingInCheck(state, move)
-		}
-		return false
+		return bishopAttacks(from, l.all)&toBit != 0 && l.keepsKingSafe(from, to, 0)
 	case Rook:
-		if (df == 0 || dr == 0) && (adf+adr > 0) && pathClearBetween(move.From, move.To) {
-			return !wouldLeaveKingInCheck(state, move)
-		}
-		return false
+		return rookAttacks(from, l.all)&toBit != 0 && l.keepsKingSafe(from, to, 0)
 	case Queen:
-		if ((df == 0 || dr == 0) || (adf == adr)) && (adf+adr > 0) && pathClearBetween(move.From, move.To) {
-			return !wouldLeaveKingInCheck(state, move)
-		}
-		return false
+		return (rookAttacks(from, l.all)|bishopAttacks(from, l.all))&toBit != 0 && l.keepsKingSafe(from, to, 0)
 	case King:
 		// normal one-square king move
-		if adf <= 1 && adr <= 1 {
-			return !wouldLeaveKingInCheck(state, move)
+		if kingAttacks[from]&toBit != 0 {
+			return l.kingStepSafe(from, to)
 		}
-
 		// castling: king moves two squares horizontally
 		if adr == 0 && adf == 2 {
-			// determine side and rights
-			if state.Turn == White {
-				// white king rank 0 expected
-				if move.From.Rank != 0 {
-					return false
-				}
-				switch df {
-				case 2:
-					// king-side
-					if !state.WhiteCanCastleKingSide {
-						return false
-					}
-					// rook must be at h1
-					rookPos := Position{File: 7, Rank: 0}
-					rook := state.Board.Squares[rookPos.File][rookPos.Rank]
-					if rook == nil || rook.Kind != Rook || rook.Color != White {
-						return false
-					}
-					// path between king and destination must be clear and not attacked
-					mid := Position{File: 5, Rank: 0}
-					destPos := move.To
-					if !pathClearBetween(move.From, destPos) {
-						return false
-					}
-					if squareAttacked(state, move.From, opposite(state.Turn)) || squareAttacked(state, mid, opposite(state.Turn)) || squareAttacked(state, destPos, opposite(state.Turn)) {
-						return false
-					}
-					return !wouldLeaveKingInCheck(state, move)
-				case -2:
-					// queen-side
-					if !state.WhiteCanCastleQueenSide {
-						return false
-					}
-					rookPos := Position{File: 0, Rank: 0}
-					rook := state.Board.Squares[rookPos.File][rookPos.Rank]
-					if rook == nil || rook.Kind != Rook || rook.Color != White {
-						return false
-					}
-					mid1 := Position{File: 3, Rank: 0}
-					destPos := move.To
-					// queen-side castling additionally requires b-file square to be empty
-					if state.Board.Squares[1][0] != nil {
-						return false
-					}
-					if !pathClearBetween(move.From, destPos) {
-						return false
-					}
-					if squareAttacked(state, move.From, opposite(state.Turn)) || squareAttacked(state, mid1, opposite(state.Turn)) || squareAttacked(state, destPos, opposite(state.Turn)) {
-						return false
-					}
-					return !wouldLeaveKingInCheck(state, move)
-				}
-			} else {
-				// black
-				if move.From.Rank != 7 {
-					return false
-				}
-				switch df {
-				case 2:
-					if !state.BlackCanCastleKingSide {
-						return false
-					}
-					rookPos := Position{File: 7, Rank: 7}
-					rook := state.Board.Squares[rookPos.File][rookPos.Rank]
-					if rook == nil || rook.Kind != Rook || rook.Color != Black {
-						return false
-					}
-					mid := Position{File: 5, Rank: 7}
-					destPos := move.To
-					if !pathClearBetween(move.From, destPos) {
-						return false
-					}
-					if squareAttacked(state, move.From, opposite(state.Turn)) || squareAttacked(state, mid, opposite(state.Turn)) || squareAttacked(state, destPos, opposite(state.Turn)) {
-						return false
-					}
-					return !wouldLeaveKingInCheck(state, move)
-				case -2:
-					if !state.BlackCanCastleQueenSide {
-						return false
-					}
-					rookPos := Position{File: 0, Rank: 7}
-					rook := state.Board.Squares[rookPos.File][rookPos.Rank]
-					if rook == nil || rook.Kind != Rook || rook.Color != Black {
-						return false
-					}
-					mid1 := Position{File: 3, Rank: 7}
-					destPos := move.To
-					// queen-side castling additionally requires b-file square to be empty
-					if state.Board.Squares[1][7] != nil {
-						return false
-					}
-					if !pathClearBetween(move.From, destPos) {
-						return false
-					}
-					if squareAttacked(state, move.From, opposite(state.Turn)) || squareAttacked(state, mid1, opposite(state.Turn)) || squareAttacked(state, destPos, opposite(state.Turn)) {
-						return false
-					}
-					return !wouldLeaveKingInCheck(state, move)
-				}
-			}
+			return l.castles(move)
 		}
 		return false
 	}
@@ -248,73 +196,26 @@ func isLegalMove(state *GameState, move Move) bool {
 	return false
 }
 
-func wouldLeaveKingInCheck(state *GameState, move Move) bool {
-	mover := state.Turn
-	opp := opposite(mover)
-
-	// Simulate only the board effects needed to evaluate king safety.
-	sim := state.Clone()
-	movedPiece := sim.Board.Squares[move.From.File][move.From.Rank]
-	if movedPiece == nil {
-		return true
-	}
-
-	dest := sim.Board.Squares[move.To.File][move.To.Rank]
-	isEnPassantCapture := movedPiece.Kind == Pawn && dest == nil && sim.HasEnPassant && move.To == sim.EnPassant
-	isCastling := movedPiece.Kind == King && move.From.Rank == move.To.Rank && (move.To.File-move.From.File == 2 || move.To.File-move.From.File == -2)
-
-	// Move the main piece.
-	sim.Board.Squares[move.To.File][move.To.Rank] = movedPiece
-	sim.Board.Squares[move.From.File][move.From.Rank] = nil
-
-	// En passant removes the pawn being passed.
-	if isEnPassantCapture {
-		captured := Position{File: move.To.File, Rank: move.From.Rank}
-		sim.Board.Squares[captured.File][captured.Rank] = nil
-	}
-
-	// Castling moves the rook as well.
-	if isCastling {
-		if mover == White {
-			if move.To.File-move.From.File == 2 {
-				rook := sim.Board.Squares[7][0]
-				sim.Board.Squares[5][0] = rook
-				sim.Board.Squares[7][0] = nil
-			} else {
-				rook := sim.Board.Squares[0][0]
-				sim.Board.Squares[3][0] = rook
-				sim.Board.Squares[0][0] = nil
-			}
-		} else {
-			if move.To.File-move.From.File == 2 {
-				rook := sim.Board.Squares[7][7]
-				sim.Board.Squares[5][7] = rook
-				sim.Board.Squares[7][7] = nil
-			} else {
-				rook := sim.Board.Squares[0][7]
-				sim.Board.Squares[3][7] = rook
-				sim.Board.Squares[0][7] = nil
-			}
-		}
-	}
-
-	kingPos, ok := findKingPosition(sim, mover)
-	if !ok {
-		return true
+func hasCastlingRight(state *GameState, color Color, side CastlingSide) bool {
+	switch {
+	case color == White && side == CastleKingSide:
+		return state.WhiteCanCastleKingSide
+	case color == White:
+		return state.WhiteCanCastleQueenSide
+	case side == CastleKingSide:
+		return state.BlackCanCastleKingSide
+	default:
+		return state.BlackCanCastleQueenSide
 	}
-	return squareAttacked(sim, kingPos, opp)
 }
 
-func findKingPosition(state *GameState, color Color) (Position, bool) {
-	for f := 0; f < 8; f++ {
-		for r := 0; r < 8; r++ {
-			p := state.Board.Squares[f][r]
-			if p != nil && p.Color == color && p.Kind == King {
-				return Position{File: f, Rank: r}, true
-			}
-		}
+// isEnPassantTarget reports whether move lands on the en passant target next to an enemy pawn.
+func isEnPassantTarget(state *GameState, move Move) bool {
+	if !state.HasEnPassant || move.To != state.EnPassant || state.Board.Squares[move.To.File][move.To.Rank] != nil {
+		return false
 	}
-	return Position{}, false
+	victim := state.Board.Squares[move.To.File][move.From.Rank]
+	return victim != nil && victim.Kind == Pawn && victim.Color != state.Turn
 }
 
 func isValidPromotionKind(kind PieceKind) bool {
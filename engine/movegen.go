@@ -0,0 +1,144 @@
+package engine
+
+var (
+	knightOffsets  = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets    = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	rookDirs       = [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	bishopDirs     = [4][2]int{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+	promotionKinds = [4]PieceKind{Queen, Rook, Bishop, Knight}
+)
+
+// LegalMoves returns every legal move for the side to move.
+// Promotions are expanded into one move per promotion piece, each with PromotionSet.
+// Moves are tested on the bitboards without being played, so LegalMoves only
+// writes to the state to pick up direct edits of Board.Squares.
+func LegalMoves(state *GameState) []Move {
+	state.sync()
+	// most positions have fewer legal moves than this, so the slice rarely grows
+	moves := make([]Move, 0, 64)
+	l := newLegality(state)
+	l.findPins()
+	for own := l.own; own != 0; own &= own - 1 {
+		moves = l.appendMovesFrom(moves, lowestSquare(own))
+	}
+	return moves
+}
+
+// LegalMovesFrom returns the legal moves of the piece on pos.
+// It returns nil when pos is empty or holds a piece of the side not to move.
+func LegalMovesFrom(state *GameState, pos Position) []Move {
+	if !onBoard(pos.File, pos.Rank) {
+		return nil
+	}
+	state.sync()
+	l := newLegality(state)
+	if l.own&squareBit(pos) == 0 {
+		return nil
+	}
+	return l.appendMovesFrom(nil, squareIndex(pos))
+}
+
+// appendMovesFrom appends the legal moves of the mover's piece on sq.
+func (l *legality) appendMovesFrom(moves []Move, sq int) []Move {
+	from := squarePosition(sq)
+	piece := l.state.Board.Squares[from.File][from.Rank]
+
+	switch piece.Kind {
+	case Pawn:
+		moves = l.appendPawnMoves(moves, from, piece.Color)
+	case Knight:
+		free := l.free(sq)
+		for _, to := range knightTargets[sq] {
+			if t := squareIndex(to); l.own&(1<<t) == 0 && (free || l.keepsKingSafe(sq, t, 0)) {
+				moves = append(moves, Move{From: from, To: to})
+			}
+		}
+	case Bishop:
+		moves = l.appendSliderMoves(moves, sq, 4, 8)
+	case Rook:
+		moves = l.appendSliderMoves(moves, sq, 0, 4)
+	case Queen:
+		moves = l.appendSliderMoves(moves, sq, 0, 8)
+	case King:
+		for _, to := range kingTargets[sq] {
+			if t := squareIndex(to); l.own&(1<<t) == 0 && l.kingStepSafe(sq, t) {
+				moves = append(moves, Move{From: from, To: to})
+			}
+		}
+		// castling candidates; castles checks rights, rook, path and attacks
+		for _, df := range [2]int{2, -2} {
+			mv := Move{From: from, To: Position{File: from.File + df, Rank: from.Rank}}
+			if onBoard(mv.To.File, mv.To.Rank) && l.castles(mv) {
+				moves = append(moves, mv)
+			}
+		}
+	}
+
+	return moves
+}
+
+// appendPawnMoves appends the pushes, then the captures towards the a-file and
+// the h-file, of the pawn on from.
+func (l *legality) appendPawnMoves(moves []Move, from Position, color Color) []Move {
+	dir, lastRank, firstRanks := 1, 7, from.Rank <= 1
+	if color == Black {
+		dir, lastRank, firstRanks = -1, 0, from.Rank >= 6
+	}
+
+	one := Position{File: from.File, Rank: from.Rank + dir}
+	if onBoard(one.File, one.Rank) && l.all&squareBit(one) == 0 {
+		moves = l.appendPawnMove(moves, from, one, 0, lastRank)
+		// a swap may have put the pawn on its own back rank, from where it may
+		// still advance two squares
+		if two := (Position{File: from.File, Rank: from.Rank + 2*dir}); firstRanks && l.all&squareBit(two) == 0 {
+			moves = l.appendPawnMove(moves, from, two, 0, lastRank)
+		}
+	}
+	for _, df := range [2]int{-1, 1} {
+		to := Position{File: from.File + df, Rank: from.Rank + dir}
+		switch {
+		case !onBoard(to.File, to.Rank):
+		case l.b.occupied[l.them]&squareBit(to) != 0:
+			moves = l.appendPawnMove(moves, from, to, 0, lastRank)
+		case isEnPassantTarget(l.state, Move{From: from, To: to}):
+			moves = l.appendPawnMove(moves, from, to, squareBit(Position{File: to.File, Rank: from.Rank}), lastRank)
+		}
+	}
+	return moves
+}
+
+// appendPawnMove appends the pawn move from from to to if it is legal,
+// expanded into the four promotions on the last rank.
+func (l *legality) appendPawnMove(moves []Move, from, to Position, victim uint64, lastRank int) []Move {
+	if !l.keepsKingSafe(squareIndex(from), squareIndex(to), victim) {
+		return moves
+	}
+	if to.Rank != lastRank {
+		return append(moves, Move{From: from, To: to})
+	}
+	for _, kind := range promotionKinds {
+		moves = append(moves, Move{From: from, To: to, Promotion: kind, PromotionSet: true})
+	}
+	return moves
+}
+
+// appendSliderMoves walks the rays in directions [first, last) from sq, nearest
+// square first; see rayMasks.
+func (l *legality) appendSliderMoves(moves []Move, sq, first, last int) []Move {
+	from, free := squarePosition(sq), l.free(sq)
+	for d := first; d < last; d++ {
+		targets := rayAttacks(d, sq, l.all) &^ l.own
+		for targets != 0 {
+			t := nearestSquare(d, targets)
+			targets &^= 1 << t
+			if free || l.keepsKingSafe(sq, t, 0) {
+				moves = append(moves, Move{From: from, To: squarePosition(t)})
+			}
+		}
+	}
+	return moves
+}
+
+func onBoard(file, rank int) bool {
+	return file >= 0 && file < 8 && rank >= 0 && rank < 8
+}
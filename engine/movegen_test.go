@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestLegalMovesStartingPosition(t *testing.T) {
+	moves := LegalMoves(NewGame())
+	if len(moves) != 20 {
+		t.Fatalf("expected 20 legal moves from the starting position, got %d", len(moves))
+	}
+
+	knight := LegalMovesFrom(NewGame(), Position{File: 1, Rank: 0}) // b1
+	if len(knight) != 2 {
+		t.Fatalf("expected 2 knight moves from b1, got %d: %+v", len(knight), knight)
+	}
+
+	if got := LegalMovesFrom(NewGame(), Position{File: 1, Rank: 7}); got != nil {
+		t.Fatalf("expected no moves for the side not to move, got %+v", got)
+	}
+	if got := LegalMovesFrom(NewGame(), Position{File: 4, Rank: 3}); got != nil {
+		t.Fatalf("expected no moves from an empty square, got %+v", got)
+	}
+}
+
+func TestLegalMovesExpandsPromotions(t *testing.T) {
+	s := &GameState{Turn: White}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White}
+	s.Board.Squares[7][7] = &Piece{Kind: King, Color: Black}
+	s.Board.Squares[0][6] = &Piece{Kind: Pawn, Color: White} // a7
+
+	moves := LegalMovesFrom(s, Position{File: 0, Rank: 6})
+	if len(moves) != 4 {
+		t.Fatalf("expected 4 promotion choices, got %d: %+v", len(moves), moves)
+	}
+	seen := make(map[PieceKind]bool)
+	for _, mv := range moves {
+		if !mv.PromotionSet {
+			t.Fatalf("expected explicit promotion flag on %+v", mv)
+		}
+		seen[mv.Promotion] = true
+	}
+	for _, kind := range []PieceKind{Queen, Rook, Bishop, Knight} {
+		if !seen[kind] {
+			t.Fatalf("missing promotion to %s", kind)
+		}
+	}
+}
+
+func TestLegalMovesIncludesCastlingAndEnPassant(t *testing.T) {
+	s := &GameState{Turn: White, WhiteCanCastleKingSide: true, WhiteCanCastleQueenSide: true}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White} // e1
+	s.Board.Squares[7][0] = &Piece{Kind: Rook, Color: White} // h1
+	s.Board.Squares[0][0] = &Piece{Kind: Rook, Color: White} // a1
+	s.Board.Squares[4][7] = &Piece{Kind: King, Color: Black} // e8
+	s.Board.Squares[4][4] = &Piece{Kind: Pawn, Color: White} // e5
+	s.Board.Squares[3][4] = &Piece{Kind: Pawn, Color: Black} // d5
+	s.HasEnPassant = true
+	s.EnPassant = Position{File: 3, Rank: 5} // d6
+
+	moves := LegalMoves(s)
+	want := []Move{
+		{From: Position{File: 4, Rank: 0}, To: Position{File: 6, Rank: 0}},
+		{From: Position{File: 4, Rank: 0}, To: Position{File: 2, Rank: 0}},
+		{From: Position{File: 4, Rank: 4}, To: Position{File: 3, Rank: 5}},
+	}
+	for _, w := range want {
+		if !containsMove(moves, w) {
+			t.Fatalf("expected %+v among legal moves", w)
+		}
+	}
+}
+
+func TestLegalMovesRespectsCheck(t *testing.T) {
+	// White king on e1 in check from a rook on e8; only king moves and the block are legal.
+	s := &GameState{Turn: White}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White}   // e1
+	s.Board.Squares[3][2] = &Piece{Kind: Knight, Color: White} // d3
+	s.Board.Squares[4][7] = &Piece{Kind: Rook, Color: Black}   // e8
+	s.Board.Squares[0][7] = &Piece{Kind: King, Color: Black}   // a8
+
+	for _, mv := range LegalMoves(s) {
+		sim := s.Clone()
+		sim.Board.Squares[mv.To.File][mv.To.Rank] = sim.Board.Squares[mv.From.File][mv.From.Rank]
+		sim.Board.Squares[mv.From.File][mv.From.Rank] = nil
+		if IsInCheck(sim, White) {
+			t.Fatalf("generated move %+v leaves the king in check", mv)
+		}
+	}
+	if !containsMove(LegalMoves(s), Move{From: Position{File: 3, Rank: 2}, To: Position{File: 4, Rank: 4}}) {
+		t.Fatalf("expected knight block on e5 to be legal")
+	}
+}
+
+func TestLegalMovesMatchesExhaustiveProbe(t *testing.T) {
+	positions := []*GameState{NewGame()}
+
+	midgame := NewGame()
+	for _, mv := range []Move{
+		{From: Position{File: 4, Rank: 1}, To: Position{File: 4, Rank: 3}},
+		{From: Position{File: 3, Rank: 6}, To: Position{File: 3, Rank: 4}},
+		{From: Position{File: 4, Rank: 3}, To: Position{File: 3, Rank: 4}},
+	} {
+		midgame.SuppressNextSwap = true // keep the line deterministic
+		if err := ApplyMove(midgame, mv); err != nil {
+			t.Fatalf("setup move %+v failed: %v", mv, err)
+		}
+	}
+	positions = append(positions, midgame)
+
+	promo := &GameState{Turn: Black}
+	promo.Board.Squares[4][7] = &Piece{Kind: King, Color: Black}
+	promo.Board.Squares[3][1] = &Piece{Kind: Pawn, Color: Black}
+	promo.Board.Squares[2][0] = &Piece{Kind: Rook, Color: White}
+	promo.Board.Squares[7][0] = &Piece{Kind: King, Color: White}
+	positions = append(positions, promo)
+
+	for i, s := range positions {
+		got := moveKeys(LegalMoves(s))
+		want := moveKeys(probeLegalMoves(s))
+		if len(got) != len(want) {
+			t.Fatalf("position %d: generator found %d moves, probe found %d\n got: %v\nwant: %v", i, len(got), len(want), got, want)
+		}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatalf("position %d: move lists differ\n got: %v\nwant: %v", i, got, want)
+			}
+		}
+	}
+}
+
+func containsMove(moves []Move, want Move) bool {
+	for _, mv := range moves {
+		if mv == want {
+			return true
+		}
+	}
+	return false
+}
+
+// probeLegalMoves is the brute-force reference: every from/to pair plus every promotion choice.
+func probeLegalMoves(s *GameState) []Move {
+	var moves []Move
+	for from := 0; from < 64; from++ {
+		for to := 0; to < 64; to++ {
+			mv := Move{From: Position{File: from / 8, Rank: from % 8}, To: Position{File: to / 8, Rank: to % 8}}
+			if mv.From == mv.To {
+				continue
+			}
+			p := s.Board.Squares[mv.From.File][mv.From.Rank]
+			if p != nil && p.Kind == Pawn && (mv.To.Rank == 0 || mv.To.Rank == 7) {
+				for _, kind := range promotionKinds {
+					promo := mv
+					promo.Promotion = kind
+					promo.PromotionSet = true
+					if isLegalMove(s, promo) {
+						moves = append(moves, promo)
+					}
+				}
+				continue
+			}
+			if isLegalMove(s, mv) {
+				moves = append(moves, mv)
+			}
+		}
+	}
+	return moves
+}
+
+func moveKeys(moves []Move) []string {
+	keys := make([]string, 0, len(moves))
+	for _, mv := range moves {
+		key := string([]byte{byte('a' + mv.From.File), byte('1' + mv.From.Rank), byte('a' + mv.To.File), byte('1' + mv.To.Rank)})
+		if mv.PromotionSet {
+			key += mv.Promotion.String()
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
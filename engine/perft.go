@@ -0,0 +1,165 @@
+package engine
+
+// SwapOutcome is one way the swap step of a move can turn out.
+type SwapOutcome struct {
+	// Targets lists the swap squares in order, as ApplyMoveWithSwaps takes
+	// them. It is empty when no swap happens.
+	Targets []Position
+	// Probability is the chance of this outcome under the state's rules,
+	// assuming the SwapSource draws uniformly.
+	Probability float64
+}
+
+// SwapOutcomes lists every distinct way the swap step of move can turn out
+// under the state's rules, i.e. the branches of the chance node that follows
+// the move. Each outcome can be played with ApplyMoveWithSwaps or
+// MakeMoveWithSwaps, and the probabilities add up to 1. The state is left as
+// it was.
+func SwapOutcomes(state *GameState, move Move) ([]SwapOutcome, error) {
+	state.sync()
+	if !isLegalMove(state, move) {
+		return nil, ErrIllegalMove
+	}
+	rules := state.rules()
+	if rules.Swaps <= 0 || rules.SwapChance <= 0 {
+		return []SwapOutcome{{Probability: 1}}, nil
+	}
+
+	root := probeSwaps(state, move, nil)
+	if !root.asked {
+		// suppressed, or nothing to swap with
+		return []SwapOutcome{{Probability: 1}}, nil
+	}
+	var outcomes []SwapOutcome
+	chance := 1.0
+	if root.rolled {
+		chance = rules.SwapChance
+		outcomes = append(outcomes, SwapOutcome{Probability: 1 - chance})
+	}
+	return appendSwapOutcomes(outcomes, state, move, nil, root, chance), nil
+}
+
+// appendSwapOutcomes expands the swap sequence prefix, whose probe is p, into
+// its complete outcomes.
+func appendSwapOutcomes(outcomes []SwapOutcome, state *GameState, move Move, prefix []Position, p *swapProbe, probability float64) []SwapOutcome {
+	if !p.asked {
+		return append(outcomes, SwapOutcome{Targets: prefix, Probability: probability})
+	}
+
+	// Under SelfCheckReroll some candidates are drawn and put back; only the
+	// ones that are actually swapped with count as branches.
+	type branch struct {
+		targets []Position
+		probe   *swapProbe
+	}
+	var branches []branch
+	for _, c := range p.next {
+		targets := append(prefix[:len(prefix):len(prefix)], c)
+		if child := probeSwaps(state, move, targets); child.swaps == len(targets) {
+			branches = append(branches, branch{targets: targets, probe: child})
+		}
+	}
+	if len(branches) == 0 {
+		return append(outcomes, SwapOutcome{Targets: prefix, Probability: probability})
+	}
+	for _, b := range branches {
+		outcomes = appendSwapOutcomes(outcomes, state, move, b.targets, b.probe, probability/float64(len(branches)))
+	}
+	return outcomes
+}
+
+// swapProbe is a swapChooser that replays prefix, then records the
+// candidates offered for the next swap and stops there.
+type swapProbe struct {
+	prefix []Position
+	calls  int
+
+	rolled bool
+	asked  bool
+	next   []Position
+	swaps  int
+}
+
+// probeSwaps plays move with the swaps in prefix and unmakes it again.
+func probeSwaps(state *GameState, move Move, prefix []Position) *swapProbe {
+	p := &swapProbe{prefix: prefix}
+	var u Undo
+	res, err := makeMove(state, move, p, &u)
+	if err != nil {
+		return p
+	}
+	if res.Swapped {
+		p.swaps = 1 + len(res.ExtraSwaps)
+	}
+	UnmakeMove(state, &u)
+	return p
+}
+
+func (p *swapProbe) choose(_ *GameState, candidates []Position) (Position, bool) {
+	p.calls++
+	if p.calls <= len(p.prefix) {
+		return p.prefix[p.calls-1], true
+	}
+	p.asked = true
+	p.next = append([]Position(nil), candidates...)
+	return Position{}, false
+}
+
+func (p *swapProbe) roll(_ *GameState, _ float64) bool {
+	p.rolled = true
+	return true
+}
+
+// Perft counts the leaf nodes of the game tree depth plies deep, treating
+// every SwapOutcome of a move as its own branch. With swaps disabled by the
+// rules it counts the same nodes as standard chess perft.
+func Perft(state *GameState, depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+	var nodes uint64
+	for _, move := range LegalMoves(state) {
+		outcomes, _ := SwapOutcomes(state, move)
+		if depth == 1 {
+			nodes += uint64(len(outcomes))
+			continue
+		}
+		for _, o := range outcomes {
+			nodes += perftAfter(state, move, o.Targets, depth-1)
+		}
+	}
+	return nodes
+}
+
+// PerftEntry is the node count below one root move and swap outcome.
+type PerftEntry struct {
+	Move  Move
+	Swaps []Position
+	Nodes uint64
+}
+
+// PerftDivide splits Perft by root move and swap outcome, in move generation
+// order, to narrow down where two move generators disagree.
+func PerftDivide(state *GameState, depth int) []PerftEntry {
+	if depth <= 0 {
+		return nil
+	}
+	var entries []PerftEntry
+	for _, move := range LegalMoves(state) {
+		outcomes, _ := SwapOutcomes(state, move)
+		for _, o := range outcomes {
+			entries = append(entries, PerftEntry{Move: move, Swaps: o.Targets, Nodes: perftAfter(state, move, o.Targets, depth-1)})
+		}
+	}
+	return entries
+}
+
+func perftAfter(state *GameState, move Move, targets []Position, depth int) uint64 {
+	_, u, err := MakeMoveWithSwaps(state, move, targets)
+	if err != nil {
+		return 0
+	}
+	nodes := Perft(state, depth)
+	UnmakeMove(state, &u)
+	return nodes
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+// chessRules turns swaps off so perft counts match standard chess.
+func chessRules(s *GameState) {
+	setRules(s, func(r *Rules) { r.Swaps = 0 })
+}
+
+func TestPerftMatchesChessWithoutSwaps(t *testing.T) {
+	cases := []struct {
+		name  string
+		fen   string
+		depth int
+		want  uint64
+	}{
+		{"start", StartFEN, 1, 20},
+		{"start", StartFEN, 2, 400},
+		{"start", StartFEN, 3, 8902},
+		{"kiwipete", kiwipete, 1, 48},
+		{"kiwipete", kiwipete, 2, 2039},
+		// en passant, promotions and discovered checks
+		{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 3, 2812},
+		{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 2, 264},
+	}
+	for _, tc := range cases {
+		s := mustParseFEN(t, tc.fen)
+		chessRules(s)
+		before := s.FEN()
+		if got := Perft(s, tc.depth); got != tc.want {
+			t.Fatalf("%s perft(%d) = %d, want %d", tc.name, tc.depth, got, tc.want)
+		}
+		if s.FEN() != before {
+			t.Fatalf("%s: perft changed the state to %s", tc.name, s.FEN())
+		}
+	}
+}
+
+func TestPerftDivideSumsToPerft(t *testing.T) {
+	s := mustParseFEN(t, kiwipete)
+	chessRules(s)
+	entries := PerftDivide(s, 2)
+	if len(entries) != 48 {
+		t.Fatalf("expected one entry per root move, got %d", len(entries))
+	}
+	var total uint64
+	for _, e := range entries {
+		total += e.Nodes
+	}
+	if total != 2039 {
+		t.Fatalf("divide entries add up to %d, want 2039", total)
+	}
+}
+
+func TestSwapPerftExpandsEverySwapTarget(t *testing.T) {
+	// every white move of the opening can swap with any of the 15 other white pieces
+	if got := Perft(NewGame(), 1); got != 20*15 {
+		t.Fatalf("swap perft(1) = %d, want %d", got, 20*15)
+	}
+	entries := PerftDivide(NewGame(), 1)
+	if len(entries) != 20*15 {
+		t.Fatalf("expected one divide entry per move and swap, got %d", len(entries))
+	}
+	if len(entries[0].Swaps) != 1 {
+		t.Fatalf("expected divide entries to carry their swap target, got %v", entries[0].Swaps)
+	}
+}
+
+func TestSwapOutcomesAreReplayable(t *testing.T) {
+	for name, setup := range map[string]func(*GameState){
+		"classic": func(*GameState) {},
+		"gentle":  func(s *GameState) { setRules(s, func(r *Rules) { r.SwapChance = 0.25 }) },
+		"double":  func(s *GameState) { setRules(s, func(r *Rules) { r.Swaps = 2 }) },
+		"reroll": func(s *GameState) {
+			setRules(s, func(r *Rules) { r.SelfCheckSwaps = SelfCheckReroll })
+		},
+	} {
+		s := mustParseFEN(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1 - 3")
+		setup(s)
+		before := s.FEN()
+		for _, move := range LegalMoves(s) {
+			outcomes, err := SwapOutcomes(s, move)
+			if err != nil {
+				t.Fatalf("%s %s: SwapOutcomes returned error: %v", name, moveText(move), err)
+			}
+			if s.FEN() != before {
+				t.Fatalf("%s %s: SwapOutcomes changed the state", name, moveText(move))
+			}
+			total := 0.0
+			for _, o := range outcomes {
+				total += o.Probability
+				res, u, err := MakeMoveWithSwaps(s, move, o.Targets)
+				if err != nil {
+					t.Fatalf("%s %s: outcome %v is not playable: %v", name, moveText(move), o.Targets, err)
+				}
+				if got := swapTargetsOf(res); !samePositions(got, o.Targets) {
+					t.Fatalf("%s %s: outcome %v played swaps %v", name, moveText(move), o.Targets, got)
+				}
+				UnmakeMove(s, &u)
+			}
+			if math.Abs(total-1) > 1e-9 {
+				t.Fatalf("%s %s: outcome probabilities add up to %v", name, moveText(move), total)
+			}
+		}
+	}
+}
+
+func TestSwapOutcomesRerollSkipsUnsafeTargets(t *testing.T) {
+	s := selfCheckSwapState(t, SelfCheckReroll)
+	outcomes, err := SwapOutcomes(s, selfCheckMove)
+	if err != nil {
+		t.Fatalf("SwapOutcomes returned error: %v", err)
+	}
+	if len(outcomes) != 1 || len(outcomes[0].Targets) != 1 || outcomes[0].Targets[0] != pawnSquare || outcomes[0].Probability != 1 {
+		t.Fatalf("expected the rerolled swap to land on %s for sure, got %v", SquareString(pawnSquare), outcomes)
+	}
+
+	// With only the unsafe king left no swap happens, and replaying that needs no target.
+	s.Board.Squares[0][1] = nil
+	outcomes, err = SwapOutcomes(s, selfCheckMove)
+	if err != nil || len(outcomes) != 1 || len(outcomes[0].Targets) != 0 {
+		t.Fatalf("expected a single unswapped outcome, got %v (%v)", outcomes, err)
+	}
+	if _, err := ApplyMoveWithSwaps(s, selfCheckMove, nil); err != nil {
+		t.Fatalf("ApplyMoveWithSwaps without targets returned error: %v", err)
+	}
+}
+
+func swapTargetsOf(res MoveResult) []Position {
+	if !res.Swapped {
+		return nil
+	}
+	targets := []Position{res.SwapB}
+	for _, step := range res.ExtraSwaps {
+		targets = append(targets, step.B)
+	}
+	return targets
+}
+
+func samePositions(a, b []Position) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
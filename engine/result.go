@@ -0,0 +1,118 @@
+package engine
+
+// CastlingSide identifies which side a castling move went to.
+type CastlingSide int
+
+const (
+	CastleNone CastlingSide = iota
+	CastleKingSide
+	CastleQueenSide
+)
+
+func (c CastlingSide) String() string {
+	switch c {
+	case CastleKingSide:
+		return "king side"
+	case CastleQueenSide:
+		return "queen side"
+	default:
+		return "none"
+	}
+}
+
+// SwapSuppression explains why a move did not trigger a swap.
+type SwapSuppression int
+
+const (
+	// SwapNotSuppressed means the swap step ran; see MoveResult.Swapped.
+	SwapNotSuppressed SwapSuppression = iota
+	// SuppressedByCheck means the move gave check.
+	SuppressedByCheck
+	// SuppressedAfterCheck means the move was the reply to a checking move.
+	SuppressedAfterCheck
+	// SuppressedNoCandidates means the mover had no other piece to swap with.
+	SuppressedNoCandidates
+	// SuppressedKingCaptured means the move captured the enemy king and ended the game.
+	SuppressedKingCaptured
+	// SuppressedByRules means the rules disable swaps altogether.
+	SuppressedByRules
+	// SuppressedByChance means the swap chance roll failed.
+	SuppressedByChance
+)
+
+func (s SwapSuppression) String() string {
+	switch s {
+	case SuppressedByCheck:
+		return "move gave check"
+	case SuppressedAfterCheck:
+		return "reply to check"
+	case SuppressedNoCandidates:
+		return "no swap candidates"
+	case SuppressedKingCaptured:
+		return "king captured"
+	case SuppressedByRules:
+		return "swaps disabled"
+	case SuppressedByChance:
+		return "swap chance"
+	default:
+		return "not suppressed"
+	}
+}
+
+// MoveResult describes everything ApplyMoveResult did to the state.
+type MoveResult struct {
+	Move  Move
+	Mover Color
+	// Piece is the moved piece as it stood before the move, i.e. before promotion.
+	Piece Piece
+
+	// HasCapture reports whether the move captured Captured on CapturedAt,
+	// which differs from Move.To for en passant captures.
+	HasCapture bool
+	Captured   Piece
+	CapturedAt Position
+	EnPassant  bool
+	Castling   CastlingSide
+
+	Promoted  bool
+	Promotion PieceKind
+
+	GivesCheck bool
+
+	// Swapped reports whether the swap step exchanged two pieces. When it did,
+	// the moved piece went from SwapA (the move's destination) to SwapB, and
+	// SwapPartner moved from SwapB to SwapA. Suppression says why no swap
+	// happened otherwise.
+	Swapped     bool
+	Suppression SwapSuppression
+	SwapA       Position
+	SwapB       Position
+	SwapPartner Piece
+	// SwapPromoted reports that the swap carried a pawn onto its last rank and
+	// BackRankPawnsPromote turned it into a queen. SwapPartner keeps the
+	// piece as it was before the promotion.
+	SwapPromoted bool
+
+	// ExtraSwaps lists the swaps after the first when Rules.Swaps is above 1.
+	ExtraSwaps []SwapStep
+}
+
+// SwapStep describes one swap: the moved piece went from A to B and Partner
+// went from B to A.
+type SwapStep struct {
+	A        Position
+	B        Position
+	Partner  Piece
+	Promoted bool
+}
+
+// FinalSquare returns the square the moved piece occupies after the swap step.
+func (r MoveResult) FinalSquare() Position {
+	if n := len(r.ExtraSwaps); n > 0 {
+		return r.ExtraSwaps[n-1].B
+	}
+	if r.Swapped {
+		return r.SwapB
+	}
+	return r.Move.To
+}
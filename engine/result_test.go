@@ -0,0 +1,121 @@
+package engine
+
+import "testing"
+
+func TestApplyMoveResultReportsSwapSquares(t *testing.T) {
+	s := &GameState{Turn: White, RandSeed: 1}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White}   // e1
+	s.Board.Squares[0][1] = &Piece{Kind: Rook, Color: White}   // a2
+	s.Board.Squares[2][2] = &Piece{Kind: Knight, Color: White} // c3
+	s.Board.Squares[7][7] = &Piece{Kind: King, Color: Black}   // h8
+
+	res, err := ApplyMoveResult(s, Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}})
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if !res.Swapped || res.Suppression != SwapNotSuppressed {
+		t.Fatalf("expected a swap, got %+v", res)
+	}
+	if res.SwapA != (Position{File: 0, Rank: 2}) {
+		t.Fatalf("expected swap to start at the destination, got %+v", res.SwapA)
+	}
+	moved := s.Board.Squares[res.SwapB.File][res.SwapB.Rank]
+	if moved == nil || moved.Kind != Rook || res.FinalSquare() != res.SwapB {
+		t.Fatalf("expected moved rook on %+v, got %+v", res.SwapB, moved)
+	}
+	partner := s.Board.Squares[res.SwapA.File][res.SwapA.Rank]
+	if partner == nil || *partner != res.SwapPartner {
+		t.Fatalf("expected swap partner %+v on destination, got %+v", res.SwapPartner, partner)
+	}
+	if res.Piece != (Piece{Kind: Rook, Color: White}) || res.Mover != White {
+		t.Fatalf("unexpected moved piece %+v / mover %s", res.Piece, res.Mover)
+	}
+}
+
+func TestApplyMoveResultReportsSuppression(t *testing.T) {
+	s := &GameState{Turn: White}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White}
+	s.Board.Squares[0][1] = &Piece{Kind: Rook, Color: White}
+	s.Board.Squares[2][2] = &Piece{Kind: Knight, Color: White}
+	s.Board.Squares[0][7] = &Piece{Kind: King, Color: Black}
+
+	res, err := ApplyMoveResult(s, Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 6}})
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if !res.GivesCheck || res.Swapped || res.Suppression != SuppressedByCheck {
+		t.Fatalf("expected check-suppressed swap, got %+v", res)
+	}
+
+	res, err = ApplyMoveResult(s, Move{From: Position{File: 0, Rank: 7}, To: Position{File: 1, Rank: 7}})
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if res.Swapped || res.Suppression != SuppressedAfterCheck {
+		t.Fatalf("expected reply-to-check suppression, got %+v", res)
+	}
+
+	lone := &GameState{Turn: White}
+	lone.Board.Squares[4][0] = &Piece{Kind: King, Color: White}
+	lone.Board.Squares[7][7] = &Piece{Kind: King, Color: Black}
+	res, err = ApplyMoveResult(lone, Move{From: Position{File: 4, Rank: 0}, To: Position{File: 4, Rank: 1}})
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if res.Swapped || res.Suppression != SuppressedNoCandidates {
+		t.Fatalf("expected no-candidate suppression, got %+v", res)
+	}
+}
+
+func TestApplyMoveResultReportsSpecialMoves(t *testing.T) {
+	s := &GameState{Turn: White, WhiteCanCastleKingSide: true, SuppressNextSwap: true}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White} // e1
+	s.Board.Squares[7][0] = &Piece{Kind: Rook, Color: White} // h1
+	s.Board.Squares[0][7] = &Piece{Kind: King, Color: Black} // a8
+
+	res, err := ApplyMoveResult(s, Move{From: Position{File: 4, Rank: 0}, To: Position{File: 6, Rank: 0}})
+	if err != nil {
+		t.Fatalf("castling returned error: %v", err)
+	}
+	if res.Castling != CastleKingSide || res.HasCapture {
+		t.Fatalf("expected king-side castling, got %+v", res)
+	}
+
+	ep := &GameState{Turn: White, SuppressNextSwap: true, HasEnPassant: true, EnPassant: Position{File: 3, Rank: 5}}
+	ep.Board.Squares[4][0] = &Piece{Kind: King, Color: White}
+	ep.Board.Squares[4][4] = &Piece{Kind: Pawn, Color: White} // e5
+	ep.Board.Squares[3][4] = &Piece{Kind: Pawn, Color: Black} // d5
+	ep.Board.Squares[7][7] = &Piece{Kind: King, Color: Black}
+
+	res, err = ApplyMoveResult(ep, Move{From: Position{File: 4, Rank: 4}, To: Position{File: 3, Rank: 5}})
+	if err != nil {
+		t.Fatalf("en passant returned error: %v", err)
+	}
+	if !res.EnPassant || !res.HasCapture || res.Captured.Kind != Pawn || res.CapturedAt != (Position{File: 3, Rank: 4}) {
+		t.Fatalf("expected en passant capture on d5, got %+v", res)
+	}
+
+	promo := &GameState{Turn: White, SuppressNextSwap: true}
+	promo.Board.Squares[4][0] = &Piece{Kind: King, Color: White}
+	promo.Board.Squares[0][6] = &Piece{Kind: Pawn, Color: White} // a7
+	promo.Board.Squares[1][7] = &Piece{Kind: Rook, Color: Black} // b8
+	promo.Board.Squares[7][5] = &Piece{Kind: King, Color: Black} // h6
+
+	res, err = ApplyMoveResult(promo, Move{From: Position{File: 0, Rank: 6}, To: Position{File: 1, Rank: 7}, Promotion: Knight, PromotionSet: true})
+	if err != nil {
+		t.Fatalf("promotion returned error: %v", err)
+	}
+	if !res.Promoted || res.Promotion != Knight || res.Piece.Kind != Pawn {
+		t.Fatalf("expected knight promotion from a pawn, got %+v", res)
+	}
+	if !res.HasCapture || res.Captured.Kind != Rook || res.CapturedAt != (Position{File: 1, Rank: 7}) {
+		t.Fatalf("expected rook capture on b8, got %+v", res)
+	}
+}
+
+func TestApplyMoveResultRejectsIllegalMove(t *testing.T) {
+	s := NewGame()
+	if _, err := ApplyMoveResult(s, Move{From: Position{File: 4, Rank: 1}, To: Position{File: 4, Rank: 4}}); err != ErrIllegalMove {
+		t.Fatalf("expected ErrIllegalMove, got %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package engine
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 type GameState struct {
 	Board                   Board
@@ -13,19 +16,135 @@ type GameState struct {
 	WhiteCanCastleQueenSide bool
 	BlackCanCastleKingSide  bool
 	BlackCanCastleQueenSide bool
+	// HalfmoveClock counts plies since the last capture or pawn move.
+	HalfmoveClock int
+	// FullmoveNumber starts at 1 and increments after each Black move.
+	FullmoveNumber int
+	// Ply counts the half-moves played; together with RandSeed it keys CounterSource draws.
+	Ply int
+	// SwapSource picks swap targets; nil means LegacySource.
+	SwapSource SwapSource
+	// Rules holds the variant options; nil means ClassicRules.
+	Rules *Rules
+
+	// positions holds the Hash of every position reached before the current one.
+	positions []uint64
+	// swapDraws counts the SwapSource draws made during the current move; see sourceChooser.
+	swapDraws int
+	// candidates is reused by applySwap so that swaps do not allocate.
+	candidates []Position
+	// boardHash is the placement part of Hash; see setSquare.
+	boardHash uint64
+	// bitboards is the board the engine plays on; Board.Squares is its public view.
+	bitboards bitboards
+	// placed is Board.Squares as the engine last left it; see sync.
+	placed [8][8]*Piece
 }
 
 var (
 	ErrIllegalMove = errors.New("illegal move")
+	ErrInvalidSwap = errors.New("invalid swap")
 )
 
+// ApplyMove validates and applies move, including the swap step.
 func ApplyMove(state *GameState, move Move) error {
+	_, err := ApplyMoveResult(state, move)
+	return err
+}
+
+// ApplyMoveResult applies move like ApplyMove and describes what happened.
+func ApplyMoveResult(state *GameState, move Move) (MoveResult, error) {
+	return makeMove(state, move, sourceSwap(), nil)
+}
+
+// ApplyMoveWithSwap applies move with an explicit swap outcome instead of a
+// random one, for replaying recorded games and analysis. target is the square
+// the moved piece swaps with, or nil when no swap took place. The usual
+// suppression rules still decide whether a swap happens; if target disagrees
+// with them or is not in SwapCandidates, ErrInvalidSwap is returned and the
+// state is left untouched. RandSeed is not advanced.
+func ApplyMoveWithSwap(state *GameState, move Move, target *Position) (MoveResult, error) {
+	if target == nil {
+		return ApplyMoveWithSwaps(state, move, nil)
+	}
+	return ApplyMoveWithSwaps(state, move, []Position{*target})
+}
+
+// ApplyMoveWithSwaps is ApplyMoveWithSwap for rules with several swaps per
+// move: targets lists the square of each swap in order. Under a SwapChance
+// below 1, an empty targets list records a move whose swap chance failed.
+func ApplyMoveWithSwaps(state *GameState, move Move, targets []Position) (MoveResult, error) {
+	var u Undo
+	return makeMoveWithSwaps(state, move, targets, &u)
+}
+
+// makeMoveWithSwaps applies move with forced swap targets and unmakes it again
+// if the targets disagree with the rules.
+func makeMoveWithSwaps(state *GameState, move Move, targets []Position, u *Undo) (MoveResult, error) {
+	chooser := &forcedChooser{move: move, targets: targets}
+	res, err := makeMove(state, move, chooser, u)
+	if err != nil {
+		return MoveResult{}, err
+	}
+
+	switch {
+	case chooser.err != nil:
+		err = chooser.err
+	case !res.Swapped && len(targets) > 0:
+		err = fmt.Errorf("%w: no swap takes place after %s (%s)", ErrInvalidSwap, moveText(move), res.Suppression)
+	case chooser.used < len(targets):
+		err = fmt.Errorf("%w: only %d of %d swaps take place after %s", ErrInvalidSwap, chooser.used, len(targets), moveText(move))
+	}
+	if err != nil {
+		UnmakeMove(state, u)
+		return MoveResult{}, err
+	}
+	return res, nil
+}
+
+// forcedChooser replays explicit swap targets and records the first mismatch.
+type forcedChooser struct {
+	move    Move
+	targets []Position
+	used    int
+	err     error
+}
+
+func (c *forcedChooser) choose(_ *GameState, candidates []Position) (Position, bool) {
+	if c.used >= len(c.targets) {
+		c.err = fmt.Errorf("%w: a swap is required after %s", ErrInvalidSwap, moveText(c.move))
+		return Position{}, false
+	}
+	target := c.targets[c.used]
+	for _, cand := range candidates {
+		if cand == target {
+			c.used++
+			return cand, true
+		}
+	}
+	c.err = fmt.Errorf("%w: %s is not a swap candidate", ErrInvalidSwap, SquareString(target))
+	return Position{}, false
+}
+
+func (c *forcedChooser) roll(_ *GameState, _ float64) bool {
+	return len(c.targets) > 0
+}
+
+// makeMove applies move, drawing swap decisions from chooser. When u is not
+// nil it records everything needed to unmake the move.
+func makeMove(state *GameState, move Move, chooser swapChooser, u *Undo) (MoveResult, error) {
 	// Step order matters. Do not reorder casually.
+	state.sync()
 	if !isLegalMove(state, move) {
-		return ErrIllegalMove
+		return MoveResult{}, ErrIllegalMove
 	}
 
+	u.begin(state)
+	state.swapDraws = 0
 	movedPiece := state.Board.Squares[move.From.File][move.From.Rank]
+	// the state was synced above, so the hash needs no second look at Squares
+	state.positions = append(state.positions, state.boardHash^state.flagsHash())
+	result := MoveResult{Move: move, Piece: *movedPiece, Mover: movedPiece.Color}
 
 	// 1. Apply the move
 	// detect en-passant capture before we overwrite squares
@@ -39,364 +158,204 @@ func ApplyMove(state *GameState, move Move) error {
 
 	// detect castling: king moving two squares horizontally
 	isCastling := false
-	var castlingSide string
+	castlingSide := CastleNone
 	if movedPiece != nil && movedPiece.Kind == King {
 		df := move.To.File - move.From.File
 		switch df {
 		case 2:
 			isCastling = true
-			castlingSide = "king"
+			castlingSide = CastleKingSide
 		case -2:
 			isCastling = true
-			castlingSide = "queen"
+			castlingSide = CastleQueenSide
 		}
 	}
+	result.Castling = castlingSide
+
+	if dest != nil {
+		result.HasCapture, result.Captured = true, *dest
+		result.CapturedAt = move.To
+	}
+
+	u.save(state, move.From)
+	u.save(state, move.To)
+	if isEnPassantCapture {
+		u.save(state, Position{File: move.To.File, Rank: move.From.Rank})
+	}
+	if isCastling {
+		rookFrom, rookTo := castlingRookSquares(move.From.Rank, castlingSide)
+		u.save(state, rookFrom)
+		u.save(state, rookTo)
+	}
 
-	state.Board.Squares[move.To.File][move.To.Rank] = movedPiece
-	state.Board.Squares[move.From.File][move.From.Rank] = nil
+	state.setSquare(move.To, movedPiece)
+	state.setSquare(move.From, nil)
 
 	// handle en-passant captured pawn removal
 	if isEnPassantCapture {
 		// captured pawn sits on same file as move.To, at the pawn's origin rank
 		captured := Position{File: move.To.File, Rank: move.From.Rank}
-		state.Board.Squares[captured.File][captured.Rank] = nil
+		if victim := state.Board.Squares[captured.File][captured.Rank]; victim != nil {
+			result.HasCapture, result.Captured = true, *victim
+		}
+		result.CapturedAt = captured
+		result.EnPassant = true
+		state.setSquare(captured, nil)
 	}
 
-	// handle castling rook movement
-	if isCastling && movedPiece != nil && movedPiece.Kind == King {
-		if movedPiece.Color == White {
-			if castlingSide == "king" {
-				// move rook from h1 to f1
-				rook := state.Board.Squares[7][0]
-				state.Board.Squares[5][0] = rook
-				state.Board.Squares[7][0] = nil
-			} else {
-				// queen-side: move rook from a1 to d1
-				rook := state.Board.Squares[0][0]
-				state.Board.Squares[3][0] = rook
-				state.Board.Squares[0][0] = nil
-			}
-		} else {
-			if castlingSide == "king" {
-				// black king-side: h8 to f8
-				rook := state.Board.Squares[7][7]
-				state.Board.Squares[5][7] = rook
-				state.Board.Squares[7][7] = nil
-			} else {
-				// black queen-side: a8 to d8
-				rook := state.Board.Squares[0][7]
-				state.Board.Squares[3][7] = rook
-				state.Board.Squares[0][7] = nil
-			}
-		}
+	// handle castling rook movement: h-file rook to the f-file, a-file rook to the d-file
+	if isCastling {
+		rookFrom, rookTo := castlingRookSquares(move.From.Rank, castlingSide)
+		state.setSquare(rookTo, state.Board.Squares[rookFrom.File][rookFrom.Rank])
+		state.setSquare(rookFrom, nil)
 	}
 
 	// Handle promotion: use explicit move promotion choice when provided, otherwise default to Queen.
 	if movedPiece != nil && movedPiece.Kind == Pawn {
-		if movedPiece.Color == White && move.To.Rank == 7 {
+		if (movedPiece.Color == White && move.To.Rank == 7) || (movedPiece.Color == Black && move.To.Rank == 0) {
 			if move.HasExplicitPromotion() {
-				movedPiece.Kind = move.Promotion
+				state.setKind(move.To, move.Promotion)
 			} else {
-				movedPiece.Kind = Queen
-			}
-		}
-		if movedPiece.Color == Black && move.To.Rank == 0 {
-			if move.HasExplicitPromotion() {
-				movedPiece.Kind = move.Promotion
-			} else {
-				movedPiece.Kind = Queen
+				state.setKind(move.To, Queen)
 			}
+			result.Promoted = true
+			result.Promotion = movedPiece.Kind
 		}
 	}
 
 	// 2. Detect check
-	givesCheck := moveGivesCheck(state, move)
+	givesCheck := moveGivesCheck(state)
+	result.GivesCheck = givesCheck
 
 	// 3. Decide swap
-	if givesCheck {
-		state.SuppressNextSwap = true
-	} else if state.SuppressNextSwap {
-		state.SuppressNextSwap = false
-	} else {
-		applySwap(state, move.To)
-	}
-
-	// update en-passant target: only valid immediately after a pawn double-move
-	state.HasEnPassant = false
-	if movedPiece != nil && movedPiece.Kind == Pawn {
-		if movedPiece.Color == White && move.From.Rank == 1 && move.To.Rank == 3 {
-			state.HasEnPassant = true
-			state.EnPassant = Position{File: move.From.File, Rank: 2}
-		}
-		if movedPiece.Color == Black && move.From.Rank == 6 && move.To.Rank == 4 {
-			state.HasEnPassant = true
-			state.EnPassant = Position{File: move.From.File, Rank: 5}
-		}
-	}
-
-	// update castling rights: moving king revokes both, moving/capturing rooks revokes corresponding side
-	if movedPiece != nil {
-		if movedPiece.Kind == King {
-			if movedPiece.Color == White {
-				state.WhiteCanCastleKingSide = false
-				state.WhiteCanCastleQueenSide = false
-			} else {
-				state.BlackCanCastleKingSide = false
-				state.BlackCanCastleQueenSide = false
+	rules := state.rules()
+	swappedPawn := false
+	switch {
+	case result.HasCapture && result.Captured.Kind == King:
+		// the game is over; nothing is left to swap for
+		result.Suppression = SuppressedKingCaptured
+	case givesCheck && rules.SuppressOnCheck:
+		result.Suppression = SuppressedByCheck
+	case state.SuppressNextSwap:
+		result.Suppression = SuppressedAfterCheck
+	case rules.Swaps <= 0 || rules.SwapChance <= 0:
+		result.Suppression = SuppressedByRules
+	case rules.SwapChance < 1 && !chooser.roll(state, rules.SwapChance):
+		result.Suppression = SuppressedByChance
+	default:
+		pos := move.To
+		for i := 0; i < rules.Swaps; i++ {
+			target, ok := applySwap(state, pos, chooser, u)
+			if !ok {
+				break
 			}
-		}
-		if movedPiece.Kind == Rook {
-			// if white rook moved from initial squares
-			if movedPiece.Color == White {
-				if move.From.File == 0 && move.From.Rank == 0 {
-					state.WhiteCanCastleQueenSide = false
-				}
-				if move.From.File == 7 && move.From.Rank == 0 {
-					state.WhiteCanCastleKingSide = false
-				}
+			// the swap partner now stands where the moved piece was
+			step := SwapStep{A: pos, B: target, Partner: *state.Board.Squares[pos.File][pos.Rank]}
+			step.Promoted = promoteSwappedPawn(state, pos) || promoteSwappedPawn(state, target)
+			swappedPawn = swappedPawn || step.Partner.Kind == Pawn
+			if i == 0 {
+				result.Swapped = true
+				result.SwapA = step.A
+				result.SwapB = step.B
+				result.SwapPartner = step.Partner
+				result.SwapPromoted = step.Promoted
 			} else {
-				if move.From.File == 0 && move.From.Rank == 7 {
-					state.BlackCanCastleQueenSide = false
-				}
-				if move.From.File == 7 && move.From.Rank == 7 {
-					state.BlackCanCastleKingSide = false
-				}
+				result.ExtraSwaps = append(result.ExtraSwaps, step)
 			}
+			pos = target
 		}
-	}
-
-	// if a rook was captured on its original square, revoke opponent rights
-	if dest != nil && dest.Kind == Rook {
-		if dest.Color == White {
-			if move.To.File == 0 && move.To.Rank == 0 {
-				state.WhiteCanCastleQueenSide = false
-			}
-			if move.To.File == 7 && move.To.Rank == 0 {
-				state.WhiteCanCastleKingSide = false
-			}
-		} else {
-			if move.To.File == 0 && move.To.Rank == 7 {
-				state.BlackCanCastleQueenSide = false
-			}
-			if move.To.File == 7 && move.To.Rank == 7 {
-				state.BlackCanCastleKingSide = false
-			}
+		if !result.Swapped {
+			result.Suppression = SuppressedNoCandidates
 		}
 	}
+	state.SuppressNextSwap = givesCheck && rules.SuppressAfterCheck
 
-	// 4. Switch turn
-	state.Turn = opposite(state.Turn)
-
-	return nil
-}
-
-func moveGivesCheck(state *GameState, move Move) bool {
-	_ = move
-	// After the move is applied (caller ensures this), check whether any piece
-	// belonging to the side who moved attacks the opponent king. This covers
-	// direct attacks by the moved piece and discovered attacks uncovered by the move.
-
-	mover := state.Turn
-	opp := opposite(mover)
-
-	// find opponent king
-	var kingPos Position
-	found := false
-	for f := 0; f < 8; f++ {
-		for r := 0; r < 8; r++ {
-			p := state.Board.Squares[f][r]
-			if p != nil && p.Kind == King && p.Color == opp {
-				kingPos = Position{File: f, Rank: r}
-				found = true
-				break
-			}
-		}
-		if found {
-			break
+	// update en-passant target: only valid immediately after a pawn double-move,
+	// and only while that pawn still stands on its destination; a swap that
+	// carries it away voids the target
+	state.HasEnPassant = false
+	if movedPiece.Kind == Pawn && !result.Swapped {
+		if dr := move.To.Rank - move.From.Rank; dr == 2 || dr == -2 {
+			state.HasEnPassant = true
+			state.EnPassant = Position{File: move.From.File, Rank: move.From.Rank + dr/2}
 		}
 	}
-	if !found {
-		return false
-	}
 
-	abs := func(x int) int {
-		if x < 0 {
-			return -x
-		}
-		return x
+	// update castling rights: a right survives only while its king and rook
+	// have never left their home squares, so any move, capture or swap that
+	// touches one of those squares revokes it
+	if movedPiece.Kind == King {
+		revokeCastlingRights(state, movedPiece.Color)
 	}
-	sign := func(x int) int {
-		if x < 0 {
-			return -1
-		}
-		if x > 0 {
-			return 1
-		}
-		return 0
+	revokeCastlingRightsAt(state, move.From)
+	revokeCastlingRightsAt(state, move.To)
+	if result.Swapped {
+		revokeCastlingRightsAt(state, result.SwapB)
 	}
-
-	pathClearBetween := func(a, b Position) bool {
-		df := b.File - a.File
-		dr := b.Rank - a.Rank
-		sF := sign(df)
-		sR := sign(dr)
-		f := a.File + sF
-		r := a.Rank + sR
-		for f != b.File || r != b.Rank {
-			if state.Board.Squares[f][r] != nil {
-				return false
-			}
-			f += sF
-			r += sR
-		}
-		return true
+	for _, step := range result.ExtraSwaps {
+		revokeCastlingRightsAt(state, step.B)
 	}
 
-	// check if a piece at pos attacks target (king)
-	attacks := func(pos, target Position, piece *Piece) bool {
-		df := target.File - pos.File
-		dr := target.Rank - pos.Rank
-		adf := abs(df)
-		adr := abs(dr)
-
-		switch piece.Kind {
-		case Rook:
-			if (pos.File == target.File || pos.Rank == target.Rank) && pathClearBetween(pos, target) {
-				return true
-			}
-		case Bishop:
-			if adf == adr && pathClearBetween(pos, target) {
-				return true
-			}
-		case Queen:
-			if (pos.File == target.File || pos.Rank == target.Rank || adf == adr) && pathClearBetween(pos, target) {
-				return true
-			}
-		case Knight:
-			if (adf == 2 && adr == 1) || (adf == 1 && adr == 2) {
-				return true
-			}
-		case Pawn:
-			if piece.Color == White {
-				if adf == 1 && dr == 1 {
-					return true
-				}
-			} else {
-				if adf == 1 && dr == -1 {
-					return true
-				}
-			}
-		case King:
-			if adf <= 1 && adr <= 1 {
-				return true
-			}
-		}
-		return false
+	// update move counters; a swap that relocates a pawn counts as a pawn move
+	if (movedPiece != nil && movedPiece.Kind == Pawn) || dest != nil || isEnPassantCapture || swappedPawn {
+		state.HalfmoveClock = 0
+	} else {
+		state.HalfmoveClock++
 	}
-
-	// scan all mover pieces to see if any attack the king
-	for f := 0; f < 8; f++ {
-		for r := 0; r < 8; r++ {
-			p := state.Board.Squares[f][r]
-			if p == nil || p.Color != mover {
-				continue
-			}
-			if attacks(Position{File: f, Rank: r}, kingPos, p) {
-				return true
-			}
-		}
+	if state.Turn == Black {
+		state.FullmoveNumber++
 	}
+	state.Ply++
 
-	return false
+	// 4. Switch turn
+	state.Turn = opposite(state.Turn)
+
+	return result, nil
 }
 
-// squareAttacked reports whether `pos` is attacked by any piece of color `by`.
-func squareAttacked(state *GameState, pos Position, by Color) bool {
-	abs := func(x int) int {
-		if x < 0 {
-			return -x
-		}
-		return x
-	}
-	sign := func(x int) int {
-		if x < 0 {
-			return -1
-		}
-		if x > 0 {
-			return 1
-		}
-		return 0
+// castlingRookSquares returns where the rook starts and ends when castling on rank.
+func castlingRookSquares(rank int, side CastlingSide) (Position, Position) {
+	if side == CastleKingSide {
+		return Position{File: 7, Rank: rank}, Position{File: 5, Rank: rank}
 	}
+	return Position{File: 0, Rank: rank}, Position{File: 3, Rank: rank}
+}
 
-	pathClearBetween := func(a, b Position) bool {
-		df := b.File - a.File
-		dr := b.Rank - a.Rank
-		sF := sign(df)
-		sR := sign(dr)
-		f := a.File + sF
-		r := a.Rank + sR
-		for f != b.File || r != b.Rank {
-			if state.Board.Squares[f][r] != nil {
-				return false
-			}
-			f += sF
-			r += sR
-		}
-		return true
+// revokeCastlingRights clears both castling rights of color.
+func revokeCastlingRights(state *GameState, color Color) {
+	if color == White {
+		state.WhiteCanCastleKingSide = false
+		state.WhiteCanCastleQueenSide = false
+	} else {
+		state.BlackCanCastleKingSide = false
+		state.BlackCanCastleQueenSide = false
 	}
+}
 
-	attacks := func(from Position, piece *Piece) bool {
-		df := pos.File - from.File
-		dr := pos.Rank - from.Rank
-		adf := abs(df)
-		adr := abs(dr)
-		switch piece.Kind {
-		case Rook:
-			if (from.File == pos.File || from.Rank == pos.Rank) && pathClearBetween(from, pos) {
-				return true
-			}
-		case Bishop:
-			if adf == adr && pathClearBetween(from, pos) {
-				return true
-			}
-		case Queen:
-			if (from.File == pos.File || from.Rank == pos.Rank || adf == adr) && pathClearBetween(from, pos) {
-				return true
-			}
-		case Knight:
-			if (adf == 2 && adr == 1) || (adf == 1 && adr == 2) {
-				return true
-			}
-		case Pawn:
-			if piece.Color == White {
-				if adf == 1 && dr == 1 {
-					return true
-				}
-			} else {
-				if adf == 1 && dr == -1 {
-					return true
-				}
-			}
-		case King:
-			if adf <= 1 && adr <= 1 {
-				return true
-			}
-		}
-		return false
+// revokeCastlingRightsAt clears the castling rights that depend on the piece
+// originally standing on pos: both rights for a king square, one for a rook square.
+func revokeCastlingRightsAt(state *GameState, pos Position) {
+	switch pos {
+	case Position{File: 4, Rank: 0}:
+		revokeCastlingRights(state, White)
+	case Position{File: 4, Rank: 7}:
+		revokeCastlingRights(state, Black)
+	case Position{File: 7, Rank: 0}:
+		state.WhiteCanCastleKingSide = false
+	case Position{File: 0, Rank: 0}:
+		state.WhiteCanCastleQueenSide = false
+	case Position{File: 7, Rank: 7}:
+		state.BlackCanCastleKingSide = false
+	case Position{File: 0, Rank: 7}:
+		state.BlackCanCastleQueenSide = false
 	}
+}
 
-	for f := 0; f < 8; f++ {
-		for r := 0; r < 8; r++ {
-			p := state.Board.Squares[f][r]
-			if p == nil || p.Color != by {
-				continue
-			}
-			if attacks(Position{File: f, Rank: r}, p) {
-				return true
-			}
-		}
-	}
-	return false
+// moveGivesCheck reports whether the side that just played attacks the
+// opponent king, directly or by discovery. The move must already be on the board.
+func moveGivesCheck(state *GameState) bool {
+	kingPos, ok := findKingPosition(state, opposite(state.Turn))
+	return ok && squareAttacked(state, kingPos, state.Turn)
 }
 
 func opposite(c Color) Color {
@@ -0,0 +1,97 @@
+package engine
+
+import "math/rand"
+
+// SwapSource picks swap targets. Sources are shared between cloned states, so
+// implementations should not keep per-game mutable state.
+type SwapSource interface {
+	// Pick returns an index in [0, n) selecting one of n swap candidates.
+	// draw counts the draws already made while resolving the current ply.
+	Pick(state *GameState, draw, n int) int
+}
+
+// CounterSource derives every draw from (RandSeed, Ply, draw) alone, so the
+// swap outcome of a ply does not depend on the path that led to it. It is the
+// default for NewGame and ParseFEN.
+type CounterSource struct{}
+
+func (CounterSource) Pick(state *GameState, draw, n int) int {
+	x := splitmix64(uint64(state.RandSeed))
+	x = splitmix64(x ^ uint64(state.Ply))
+	x = splitmix64(x ^ uint64(draw))
+	// map the 64-bit value onto [0, n) without a modulo
+	hi := (x >> 32) * uint64(n)
+	return int(hi >> 32)
+}
+
+// LegacySource reproduces the original swap randomness: each draw seeds
+// math/rand with RandSeed and then advances RandSeed, so outcomes depend on
+// how many swaps happened earlier in the game. A GameState without a
+// SwapSource uses it.
+type LegacySource struct{}
+
+func (LegacySource) Pick(state *GameState, draw, n int) int {
+	_ = draw
+	rng := rand.New(rand.NewSource(state.RandSeed))
+	idx := rng.Intn(n)
+	state.RandSeed++
+	return idx
+}
+
+// ScriptedSource replays fixed candidate indices, for tests. Picks[ply] lists
+// the indices for that ply's draws in order. Missing entries pick index 0 and
+// indices past the candidate count wrap around.
+type ScriptedSource struct {
+	Picks map[int][]int
+}
+
+func (s ScriptedSource) Pick(state *GameState, draw, n int) int {
+	picks := s.Picks[state.Ply]
+	if draw >= len(picks) {
+		return 0
+	}
+	idx := picks[draw] % n
+	if idx < 0 {
+		idx += n
+	}
+	return idx
+}
+
+func (g *GameState) swapSource() SwapSource {
+	if g.SwapSource == nil {
+		return LegacySource{}
+	}
+	return g.SwapSource
+}
+
+// sourceChooser draws every decision of one move from the state's SwapSource,
+// counting the draws made so far in state.swapDraws. It holds no state of its
+// own, so passing it as a swapChooser does not allocate.
+type sourceChooser struct{}
+
+// sourceSwap returns a chooser that draws from the state's SwapSource.
+func sourceSwap() swapChooser {
+	return sourceChooser{}
+}
+
+func (sourceChooser) choose(state *GameState, candidates []Position) (Position, bool) {
+	idx := state.swapSource().Pick(state, state.swapDraws, len(candidates))
+	state.swapDraws++
+	return candidates[idx], true
+}
+
+// chanceResolution is the number of buckets a swap chance is rolled with.
+const chanceResolution = 1 << 20
+
+func (sourceChooser) roll(state *GameState, chance float64) bool {
+	idx := state.swapSource().Pick(state, state.swapDraws, chanceResolution)
+	state.swapDraws++
+	return float64(idx) < chance*chanceResolution
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLegacySourceMatchesOriginalSwapRandomness(t *testing.T) {
+	s := forcedSwapState() // no SwapSource: legacy
+	s.RandSeed = 42
+	move := Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}}
+	candidates := SwapCandidates(s, move.From)
+	want := candidates[rand.New(rand.NewSource(42)).Intn(len(candidates))]
+
+	res, err := ApplyMoveResult(s, move)
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if res.SwapB != want {
+		t.Fatalf("expected legacy swap with %s, got %s", SquareString(want), SquareString(res.SwapB))
+	}
+	if s.RandSeed != 43 {
+		t.Fatalf("expected legacy source to advance RandSeed, got %d", s.RandSeed)
+	}
+}
+
+func TestCounterSourceDependsOnlyOnSeedAndPly(t *testing.T) {
+	move := Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}}
+
+	// The source never advances RandSeed, so earlier swaps cannot change the
+	// outcome at a given ply: equal (seed, ply) must give equal swaps.
+	var first Position
+	for i := 0; i < 3; i++ {
+		s := forcedSwapState()
+		s.SwapSource = CounterSource{}
+		s.RandSeed = 99
+		s.Ply = 12
+		res, err := ApplyMoveResult(s, move)
+		if err != nil {
+			t.Fatalf("ApplyMoveResult returned error: %v", err)
+		}
+		if s.RandSeed != 99 {
+			t.Fatalf("expected counter source to leave RandSeed alone, got %d", s.RandSeed)
+		}
+		if s.Ply != 13 {
+			t.Fatalf("expected ply to advance to 13, got %d", s.Ply)
+		}
+		if i == 0 {
+			first = res.SwapB
+		} else if res.SwapB != first {
+			t.Fatalf("expected identical swap for identical seed and ply, got %s and %s", SquareString(first), SquareString(res.SwapB))
+		}
+	}
+
+	seen := make(map[int]bool)
+	s := &GameState{RandSeed: 7}
+	for ply := 0; ply < 64; ply++ {
+		s.Ply = ply
+		idx := CounterSource{}.Pick(s, 0, 5)
+		if idx < 0 || idx >= 5 {
+			t.Fatalf("pick %d out of range at ply %d", idx, ply)
+		}
+		seen[idx] = true
+	}
+	if len(seen) < 4 {
+		t.Fatalf("expected counter source to spread picks over candidates, saw %d distinct", len(seen))
+	}
+}
+
+func TestNewGameUsesCounterSourceAndPly(t *testing.T) {
+	s := NewGame()
+	if _, ok := s.SwapSource.(CounterSource); !ok {
+		t.Fatalf("expected NewGame to use CounterSource, got %T", s.SwapSource)
+	}
+	parsed, err := ParseFEN("4k3/8/8/8/8/8/8/4K3 b - - 0 10")
+	if err != nil {
+		t.Fatalf("ParseFEN returned error: %v", err)
+	}
+	if parsed.Ply != 19 {
+		t.Fatalf("expected ply 19 from fullmove 10 with black to move, got %d", parsed.Ply)
+	}
+	if clone := parsed.Clone(); clone.Ply != parsed.Ply || clone.SwapSource != parsed.SwapSource {
+		t.Fatalf("expected Clone to copy ply and swap source")
+	}
+}
+
+func TestScriptedSourceReplaysPicks(t *testing.T) {
+	move := Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}}
+	candidates := SwapCandidates(forcedSwapState(), move.From)
+
+	for idx, want := range candidates {
+		s := forcedSwapState()
+		s.Ply = 4
+		s.SwapSource = ScriptedSource{Picks: map[int][]int{4: {idx}}}
+		res, err := ApplyMoveResult(s, move)
+		if err != nil {
+			t.Fatalf("ApplyMoveResult returned error: %v", err)
+		}
+		if res.SwapB != want {
+			t.Fatalf("expected scripted pick %d to swap with %s, got %s", idx, SquareString(want), SquareString(res.SwapB))
+		}
+	}
+
+	if got := (ScriptedSource{Picks: map[int][]int{0: {-1}}}).Pick(&GameState{}, 0, 3); got != 2 {
+		t.Fatalf("expected negative scripted index to wrap to 2, got %d", got)
+	}
+	if got := (ScriptedSource{}).Pick(&GameState{}, 0, 3); got != 0 {
+		t.Fatalf("expected missing scripted pick to default to 0, got %d", got)
+	}
+}
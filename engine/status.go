@@ -1,7 +1,82 @@
 package engine
 
+// Outcome describes how a game has ended.
+type Outcome int
+
+const (
+	OutcomeNone Outcome = iota
+	OutcomeCheckmate
+	OutcomeStalemate
+	OutcomeFiftyMoveRule
+	OutcomeThreefoldRepetition
+	OutcomeInsufficientMaterial
+	// OutcomeKingCaptured means the side to move lost its king after a
+	// self-check swap; see SelfCheckAllow.
+	OutcomeKingCaptured
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeCheckmate:
+		return "checkmate"
+	case OutcomeStalemate:
+		return "stalemate"
+	case OutcomeFiftyMoveRule:
+		return "fifty-move rule"
+	case OutcomeThreefoldRepetition:
+		return "threefold repetition"
+	case OutcomeInsufficientMaterial:
+		return "insufficient material"
+	case OutcomeKingCaptured:
+		return "king captured"
+	default:
+		return "in play"
+	}
+}
+
+// IsDraw reports whether the outcome ends the game without a winner.
+func (o Outcome) IsDraw() bool {
+	switch o {
+	case OutcomeStalemate, OutcomeFiftyMoveRule, OutcomeThreefoldRepetition, OutcomeInsufficientMaterial:
+		return true
+	default:
+		return false
+	}
+}
+
+// GameOutcome reports how the game has ended, or OutcomeNone while it continues.
+// A captured king, then checkmate and stalemate take precedence over the draw rules.
+func GameOutcome(state *GameState) Outcome {
+	state.sync()
+	if _, ok := findKingPosition(state, state.Turn); !ok {
+		return OutcomeKingCaptured
+	}
+	if !hasAnyLegalMove(state, state.Turn) {
+		if isInCheck(state, state.Turn) {
+			return OutcomeCheckmate
+		}
+		return OutcomeStalemate
+	}
+
+	switch {
+	case IsInsufficientMaterial(state):
+		return OutcomeInsufficientMaterial
+	case IsFiftyMoveDraw(state):
+		return OutcomeFiftyMoveRule
+	case IsThreefoldRepetition(state):
+		return OutcomeThreefoldRepetition
+	default:
+		return OutcomeNone
+	}
+}
+
 // IsInCheck reports whether the given side's king is currently attacked.
 func IsInCheck(state *GameState, color Color) bool {
+	state.sync()
+	return isInCheck(state, color)
+}
+
+func isInCheck(state *GameState, color Color) bool {
 	kingPos, ok := findKingPosition(state, color)
 	if !ok {
 		// Invalid state (missing king) is treated as "in check".
@@ -11,27 +86,19 @@ func IsInCheck(state *GameState, color Color) bool {
 }
 
 func hasAnyLegalMove(state *GameState, color Color) bool {
-	tmp := state.Clone()
-	tmp.Turn = color
-
-	for f := 0; f < 8; f++ {
-		for r := 0; r < 8; r++ {
-			p := tmp.Board.Squares[f][r]
-			if p == nil || p.Color != color {
-				continue
-			}
-			from := Position{File: f, Rank: r}
-			for tf := 0; tf < 8; tf++ {
-				for tr := 0; tr < 8; tr++ {
-					to := Position{File: tf, Rank: tr}
-					if from == to {
-						continue
-					}
-					if isLegalMove(tmp, Move{From: from, To: to}) {
-						return true
-					}
-				}
-			}
+	if state.Turn != color {
+		turn := state.Turn
+		state.Turn = color
+		defer func() { state.Turn = turn }()
+	}
+
+	// a queen has the most moves from one square, 27, so buf never grows
+	var buf [32]Move
+	l := newLegality(state)
+	l.findPins()
+	for own := l.own; own != 0; own &= own - 1 {
+		if len(l.appendMovesFrom(buf[:0], lowestSquare(own))) > 0 {
+			return true
 		}
 	}
 	return false
@@ -39,12 +106,14 @@ func hasAnyLegalMove(state *GameState, color Color) bool {
 
 // IsCheckmate reports whether the side to move is checkmated.
 func IsCheckmate(state *GameState) bool {
+	state.sync()
 	color := state.Turn
-	return IsInCheck(state, color) && !hasAnyLegalMove(state, color)
+	return isInCheck(state, color) && !hasAnyLegalMove(state, color)
 }
 
 // IsStalemate reports whether the side to move has no legal moves while not in check.
 func IsStalemate(state *GameState) bool {
+	state.sync()
 	color := state.Turn
-	return !IsInCheck(state, color) && !hasAnyLegalMove(state, color)
+	return !isInCheck(state, color) && !hasAnyLegalMove(state, color)
 }
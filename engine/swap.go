@@ -1,38 +1,169 @@
 package engine
 
-import "math/rand"
+// swapChooser makes the random decisions of one move's swap step.
+type swapChooser interface {
+	// choose picks the swap target among candidates. It returns false to skip the swap.
+	choose(state *GameState, candidates []Position) (Position, bool)
+	// roll reports whether a swap that happens with probability chance takes place.
+	// It is only consulted for chances strictly between 0 and 1.
+	roll(state *GameState, chance float64) bool
+}
 
-func applySwap(state *GameState, movedPos Position) {
-	movedPiece := state.Board.Squares[movedPos.File][movedPos.Rank]
-	if movedPiece == nil {
-		return
+// SwapCandidates returns the squares the piece on pos may swap with: every
+// other piece of the same color, in file-major board order, less those the
+// state's rules forbid. Unless the rules allow self-check swaps, targets that
+// would expose the own king are left out.
+func SwapCandidates(state *GameState, pos Position) []Position {
+	state.sync()
+	candidates := appendSwapCandidates(nil, state, pos)
+	if len(candidates) == 0 {
+		return nil
 	}
+	return candidates
+}
 
-	var candidates []Position
+// appendSwapCandidates appends the SwapCandidates of pos to candidates.
+func appendSwapCandidates(candidates []Position, state *GameState, pos Position) []Position {
+	start := len(candidates)
+	candidates = appendSwapTargets(candidates, state, pos)
+	if state.rules().SelfCheckSwaps == SelfCheckAllow {
+		return candidates
+	}
+	safe := candidates[:start]
+	for _, c := range candidates[start:] {
+		if !swapExposesKing(state, pos, c) {
+			safe = append(safe, c)
+		}
+	}
+	return safe
+}
 
-	for f := 0; f < 8; f++ {
-		for r := 0; r < 8; r++ {
-			if f == movedPos.File && r == movedPos.Rank {
-				continue
-			}
-			p := state.Board.Squares[f][r]
-			if p != nil && p.Color == movedPiece.Color {
-				candidates = append(candidates, Position{f, r})
-			}
+// appendSwapTargets appends every other piece of the same color as the piece
+// on pos that the rules let it swap with, ignoring king safety.
+func appendSwapTargets(candidates []Position, state *GameState, pos Position) []Position {
+	p := state.Board.Squares[pos.File][pos.Rank]
+	if p == nil {
+		return candidates
+	}
+	own := state.bitboards.occupied[p.Color] &^ squareBit(pos)
+	if rules := state.rules(); rules.KingSwaps && rules.BackRankPawns != BackRankPawnsExclude && rules.Candidates == nil {
+		// every other piece of the color qualifies, as CanSwap would find
+		for ; own != 0; own &= own - 1 {
+			candidates = append(candidates, squarePosition(lowestSquare(own)))
+		}
+		return candidates
+	}
+	for ; own != 0; own &= own - 1 {
+		if c := squarePosition(lowestSquare(own)); CanSwap(state, pos, c) {
+			candidates = append(candidates, c)
 		}
 	}
+	return candidates
+}
 
-	if len(candidates) == 0 {
-		return
+// CanSwap reports whether the state's rules let the pieces on a and b swap,
+// ignoring king safety. They must be two pieces of the same color.
+func CanSwap(state *GameState, a, b Position) bool {
+	pa, pb := state.Board.Squares[a.File][a.Rank], state.Board.Squares[b.File][b.Rank]
+	if a == b || pa == nil || pb == nil || pa.Color != pb.Color {
+		return false
 	}
+	rules := state.rules()
+	if (pa.Kind == King || pb.Kind == King) && !rules.KingSwaps {
+		return false
+	}
+	if rules.BackRankPawns == BackRankPawnsExclude &&
+		((pa.Kind == Pawn && isBackRank(b.Rank)) || (pb.Kind == Pawn && isBackRank(a.Rank))) {
+		return false
+	}
+	return rules.Candidates == nil || rules.Candidates(state, a, b)
+}
 
-	rng := rand.New(rand.NewSource(state.RandSeed))
-	idx := rng.Intn(len(candidates))
-	target := candidates[idx]
+// swapExposesKing reports whether swapping the pieces on a and b would leave
+// the king of their color attacked.
+func swapExposesKing(state *GameState, a, b Position) bool {
+	color := state.Board.Squares[a.File][a.Rank].Color
+	swapSquares(state, a, b)
+	exposed := isInCheck(state, color)
+	swapSquares(state, a, b)
+	return exposed
+}
 
-	// swap
-	state.Board.Squares[target.File][target.Rank], state.Board.Squares[movedPos.File][movedPos.Rank] =
-		state.Board.Squares[movedPos.File][movedPos.Rank], state.Board.Squares[target.File][target.Rank]
+// hasSafeSwap reports whether any of candidates can swap with pos without
+// exposing their king.
+func hasSafeSwap(state *GameState, pos Position, candidates []Position) bool {
+	for _, c := range candidates {
+		if !swapExposesKing(state, pos, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBackRank(rank int) bool {
+	return rank == 0 || rank == 7
+}
+
+// promoteSwappedPawn promotes a pawn standing on its last rank to a queen when
+// the rules ask for it, and reports whether it did.
+func promoteSwappedPawn(state *GameState, pos Position) bool {
+	p := state.Board.Squares[pos.File][pos.Rank]
+	if state.rules().BackRankPawns != BackRankPawnsPromote || p == nil || p.Kind != Pawn {
+		return false
+	}
+	if (p.Color == White && pos.Rank != 7) || (p.Color == Black && pos.Rank != 0) {
+		return false
+	}
+	state.setKind(pos, Queen)
+	return true
+}
+
+func swapSquares(state *GameState, a, b Position) {
+	pa, pb := state.Board.Squares[a.File][a.Rank], state.Board.Squares[b.File][b.Rank]
+	state.setSquare(a, pb)
+	state.setSquare(b, pa)
+}
 
-	state.RandSeed++
+// applySwap swaps the piece on movedPos with a same-colored piece picked by choose,
+// following the self-check policy of the state's rules.
+// It returns the square the moved piece ended up on and whether a swap happened.
+func applySwap(state *GameState, movedPos Position, chooser swapChooser, u *Undo) (Position, bool) {
+	reroll := state.rules().SelfCheckSwaps == SelfCheckReroll
+	var candidates []Position
+	if reroll {
+		candidates = appendSwapTargets(state.candidates[:0], state, movedPos)
+	} else {
+		candidates = appendSwapCandidates(state.candidates[:0], state, movedPos)
+	}
+	// keep the grown buffer for the next swap
+	state.candidates = candidates
+	// without a safe target there is nothing to draw, and no draw is spent,
+	// so the outcome is the same as when there are no candidates
+	if reroll && !hasSafeSwap(state, movedPos, candidates) {
+		return Position{}, false
+	}
+
+	for len(candidates) > 0 {
+		target, ok := chooser.choose(state, candidates)
+		if !ok {
+			return Position{}, false
+		}
+		if reroll && swapExposesKing(state, movedPos, target) {
+			// drop the unsafe target and draw again among the rest
+			rest := candidates[:0]
+			for _, c := range candidates {
+				if c != target {
+					rest = append(rest, c)
+				}
+			}
+			candidates = rest
+			continue
+		}
+
+		u.save(state, movedPos)
+		u.save(state, target)
+		swapSquares(state, movedPos, target)
+		return target, true
+	}
+	return Position{}, false
 }
@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func forcedSwapState() *GameState {
+	s := &GameState{Turn: White, RandSeed: 1}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White}   // e1
+	s.Board.Squares[0][1] = &Piece{Kind: Rook, Color: White}   // a2
+	s.Board.Squares[2][2] = &Piece{Kind: Knight, Color: White} // c3
+	s.Board.Squares[6][1] = &Piece{Kind: Pawn, Color: White}   // g2
+	s.Board.Squares[7][7] = &Piece{Kind: King, Color: Black}   // h8
+	s.Board.Squares[3][6] = &Piece{Kind: Pawn, Color: Black}   // d7
+	return s
+}
+
+func TestSwapCandidatesListsSameColoredPieces(t *testing.T) {
+	s := forcedSwapState()
+	got := SwapCandidates(s, Position{File: 0, Rank: 1})
+	want := []Position{{File: 2, Rank: 2}, {File: 4, Rank: 0}, {File: 6, Rank: 1}}
+	if len(got) != len(want) {
+		t.Fatalf("expected candidates %+v, got %+v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected candidates %+v, got %+v", want, got)
+		}
+	}
+	if SwapCandidates(s, Position{File: 4, Rank: 4}) != nil {
+		t.Fatalf("expected no candidates for an empty square")
+	}
+}
+
+func TestCanSwapAgreesWithSwapCandidates(t *testing.T) {
+	s := forcedSwapState()
+	setRules(s, func(r *Rules) { r.KingSwaps = false })
+	rook, king, blackPawn := Position{File: 0, Rank: 1}, Position{File: 4, Rank: 0}, Position{File: 3, Rank: 6}
+	if !CanSwap(s, rook, Position{File: 2, Rank: 2}) {
+		t.Fatalf("expected the rook to swap with the knight")
+	}
+	for _, b := range []Position{rook, king, blackPawn, {File: 4, Rank: 4}} {
+		if CanSwap(s, rook, b) {
+			t.Fatalf("expected the rook not to swap with %s", SquareString(b))
+		}
+	}
+}
+
+func TestApplyMoveWithSwapUsesExplicitTarget(t *testing.T) {
+	for _, target := range SwapCandidates(forcedSwapState(), Position{File: 0, Rank: 1}) {
+		s := forcedSwapState()
+		target := target
+		res, err := ApplyMoveWithSwap(s, Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}}, &target)
+		if err != nil {
+			t.Fatalf("ApplyMoveWithSwap(%s) returned error: %v", SquareString(target), err)
+		}
+		if !res.Swapped || res.SwapB != target {
+			t.Fatalf("expected swap with %s, got %+v", SquareString(target), res)
+		}
+		if p := s.Board.Squares[target.File][target.Rank]; p == nil || p.Kind != Rook {
+			t.Fatalf("expected rook on %s, got %+v", SquareString(target), p)
+		}
+		if s.RandSeed != 1 {
+			t.Fatalf("expected forced swap to leave RandSeed alone, got %d", s.RandSeed)
+		}
+	}
+}
+
+func TestApplyMoveWithSwapRejectsInvalidTargets(t *testing.T) {
+	move := Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}}
+	for name, target := range map[string]*Position{
+		"opponent piece": {File: 3, Rank: 6},
+		"empty square":   {File: 5, Rank: 5},
+		"moved piece":    {File: 0, Rank: 2},
+		"missing target": nil,
+	} {
+		s := forcedSwapState()
+		before := s.FEN()
+		if _, err := ApplyMoveWithSwap(s, move, target); !errors.Is(err, ErrInvalidSwap) {
+			t.Fatalf("%s: expected ErrInvalidSwap, got %v", name, err)
+		}
+		if s.FEN() != before || s.Turn != White {
+			t.Fatalf("%s: expected state to be untouched, got %s", name, s.FEN())
+		}
+	}
+
+	s := forcedSwapState()
+	if _, err := ApplyMoveWithSwap(s, Move{From: Position{File: 0, Rank: 1}, To: Position{File: 1, Rank: 2}}, nil); !errors.Is(err, ErrIllegalMove) {
+		t.Fatalf("expected ErrIllegalMove for an illegal move, got %v", err)
+	}
+}
+
+func TestApplyMoveWithSwapFollowsSuppressionRules(t *testing.T) {
+	// a2a7 gives check to a king on a8, so no swap may be recorded.
+	check := forcedSwapState()
+	check.Board.Squares[7][7] = nil
+	check.Board.Squares[0][7] = &Piece{Kind: King, Color: Black}
+	checkMove := Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 6}}
+	target := Position{File: 2, Rank: 2}
+	if _, err := ApplyMoveWithSwap(check.Clone(), checkMove, &target); !errors.Is(err, ErrInvalidSwap) {
+		t.Fatalf("expected ErrInvalidSwap for a swap after check, got %v", err)
+	}
+	res, err := ApplyMoveWithSwap(check, checkMove, nil)
+	if err != nil {
+		t.Fatalf("expected unswapped check move to apply, got %v", err)
+	}
+	if res.Suppression != SuppressedByCheck || !check.SuppressNextSwap {
+		t.Fatalf("expected check suppression, got %+v", res)
+	}
+
+	// The reply is suppressed as well.
+	reply := Move{From: Position{File: 0, Rank: 7}, To: Position{File: 1, Rank: 7}}
+	if _, err := ApplyMoveWithSwap(check, reply, nil); err != nil {
+		t.Fatalf("expected unswapped reply to apply, got %v", err)
+	}
+	if check.SuppressNextSwap {
+		t.Fatalf("expected suppression to be consumed by the reply")
+	}
+}
+
+func TestSwapRevokesCastlingRights(t *testing.T) {
+	const fen = "r3k2r/8/8/8/8/8/8/RN2K1NR w KQkq - 0 1 - 1"
+	knightMove := Move{From: Position{File: 6, Rank: 0}, To: Position{File: 5, Rank: 2}} // g1f3
+
+	for name, tc := range map[string]struct {
+		target Position
+		want   string
+	}{
+		"king swapped off e1":    {target: Position{File: 4, Rank: 0}, want: "kq"},
+		"rook swapped off h1":    {target: Position{File: 7, Rank: 0}, want: "Qkq"},
+		"rook swapped off a1":    {target: Position{File: 0, Rank: 0}, want: "Kkq"},
+		"swap with other knight": {target: Position{File: 1, Rank: 0}, want: "KQkq"},
+	} {
+		s := mustParseFEN(t, fen)
+		target := tc.target
+		if _, err := ApplyMoveWithSwap(s, knightMove, &target); err != nil {
+			t.Fatalf("%s: ApplyMoveWithSwap returned error: %v", name, err)
+		}
+		if got := strings.Fields(s.FEN())[2]; got != tc.want {
+			t.Fatalf("%s: expected castling rights %q, got %q", name, tc.want, got)
+		}
+	}
+}
+
+func TestCastlingRequiresOriginalKingAndRook(t *testing.T) {
+	// The a1 rook moves to b1 and swaps with the h1 rook: a rook still stands on
+	// h1, but it is not the original one, so white may no longer castle king-side.
+	s := mustParseFEN(t, "4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1 - 1")
+	target := Position{File: 7, Rank: 0}
+	if _, err := ApplyMoveWithSwap(s, Move{From: Position{File: 0, Rank: 0}, To: Position{File: 1, Rank: 0}}, &target); err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if p := s.Board.Squares[7][0]; p == nil || p.Kind != Rook {
+		t.Fatalf("expected a rook on h1 after the swap, got %+v", p)
+	}
+	s.SuppressNextSwap = true
+	if err := ApplyMove(s, Move{From: Position{File: 4, Rank: 7}, To: Position{File: 3, Rank: 7}}); err != nil {
+		t.Fatalf("black reply failed: %v", err)
+	}
+	if containsMove(LegalMoves(s), Move{From: Position{File: 4, Rank: 0}, To: Position{File: 6, Rank: 0}}) {
+		t.Fatalf("expected castling with a swapped-in rook to be illegal")
+	}
+
+	// A king that is not on e1 cannot castle even if the FEN grants the right.
+	s = mustParseFEN(t, "4k3/8/8/8/8/8/8/R2K3R w KQ - 0 1 - 1")
+	for _, mv := range LegalMovesFrom(s, Position{File: 3, Rank: 0}) {
+		if mv.To.Rank == 0 && (mv.To.File == 1 || mv.To.File == 5) {
+			t.Fatalf("expected no castling from d1, got %+v", mv)
+		}
+	}
+}
+
+func TestSwapVoidsEnPassantTarget(t *testing.T) {
+	const fen = "1n2k3/3p4/8/4P3/8/8/8/4K3 b - - 0 1 - 1"
+	double := Move{From: Position{File: 3, Rank: 6}, To: Position{File: 3, Rank: 4}}  // d7d5
+	capture := Move{From: Position{File: 4, Rank: 4}, To: Position{File: 3, Rank: 5}} // e5d6
+
+	// The swap carries the d5 pawn to b8 and leaves a knight on d5.
+	s := mustParseFEN(t, fen)
+	target := Position{File: 1, Rank: 7}
+	if _, err := ApplyMoveWithSwap(s, double, &target); err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if s.HasEnPassant {
+		t.Fatalf("expected swap to void the en passant target, got %s", s.FEN())
+	}
+	if containsMove(LegalMoves(s), capture) {
+		t.Fatalf("expected no en passant capture after the pawn was swapped away")
+	}
+
+	// Without a swap the pawn stays on d5 and may be taken en passant.
+	s = mustParseFEN(t, fen)
+	s.SuppressNextSwap = true
+	if err := ApplyMove(s, double); err != nil {
+		t.Fatalf("ApplyMove returned error: %v", err)
+	}
+	if !s.HasEnPassant || s.EnPassant != (Position{File: 3, Rank: 5}) {
+		t.Fatalf("expected en passant target d6, got %s", s.FEN())
+	}
+	s.SuppressNextSwap = true
+	res, err := ApplyMoveResult(s, capture)
+	if err != nil {
+		t.Fatalf("en passant capture failed: %v", err)
+	}
+	if !res.EnPassant || !res.HasCapture || res.Captured.Kind != Pawn || res.CapturedAt != (Position{File: 3, Rank: 4}) {
+		t.Fatalf("expected en passant capture of the d5 pawn, got %+v", res)
+	}
+}
+
+func TestEnPassantRequiresEnemyPawn(t *testing.T) {
+	s := mustParseFEN(t, "4k3/8/8/3nP3/8/8/8/4K3 w - d6 0 1 - 1")
+	if containsMove(LegalMoves(s), Move{From: Position{File: 4, Rank: 4}, To: Position{File: 3, Rank: 5}}) {
+		t.Fatalf("expected en passant onto d6 to need a pawn on d5")
+	}
+}
@@ -0,0 +1,135 @@
+package engine
+
+// Undo records what MakeMove changed so UnmakeMove can restore the state
+// exactly: moved, captured, castled, swapped and promoted pieces, castling and
+// en passant rights, SuppressNextSwap, RandSeed, the move counters and the
+// repetition history. Together MakeMove and UnmakeMove do not allocate for
+// moves that touch up to undoInline squares, which covers every move with up
+// to two swaps; further swaps are listed in MoveResult.ExtraSwaps and spill
+// into an overflow slice.
+type Undo struct {
+	squares  [undoInline]savedSquare
+	n        int
+	overflow []savedSquare
+
+	turn                    Color
+	suppressNextSwap        bool
+	randSeed                int64
+	hasEnPassant            bool
+	enPassant               Position
+	whiteCanCastleKingSide  bool
+	whiteCanCastleQueenSide bool
+	blackCanCastleKingSide  bool
+	blackCanCastleQueenSide bool
+	halfmoveClock           int
+	fullmoveNumber          int
+	ply                     int
+	positions               int
+	bitboards               bitboards
+	boardHash               uint64
+}
+
+const undoInline = 8
+
+// savedSquare remembers a square's piece pointer and that piece's value, since
+// promotions change pieces in place.
+type savedSquare struct {
+	piece *Piece
+	// sq is the squareIndex of the square; kind and color the piece's value.
+	// They are kept small so that an Undo stays cheap to copy.
+	sq    uint8
+	kind  uint8
+	color uint8
+}
+
+// MakeMove applies move like ApplyMoveResult and returns an Undo for UnmakeMove.
+func MakeMove(state *GameState, move Move) (MoveResult, Undo, error) {
+	var u Undo
+	res, err := makeMove(state, move, sourceSwap(), &u)
+	return res, u, err
+}
+
+// MakeMoveWithSwaps is MakeMove with explicit swap targets, validated like
+// ApplyMoveWithSwaps. On error the state is left untouched.
+func MakeMoveWithSwaps(state *GameState, move Move, targets []Position) (MoveResult, Undo, error) {
+	var u Undo
+	res, err := makeMoveWithSwaps(state, move, targets, &u)
+	return res, u, err
+}
+
+// UnmakeMove restores the state to what it was before the MakeMove that
+// returned u. Moves must be unmade in reverse order.
+func UnmakeMove(state *GameState, u *Undo) {
+	for i := len(u.overflow) - 1; i >= 0; i-- {
+		u.overflow[i].restore(state)
+	}
+	for i := u.n - 1; i >= 0; i-- {
+		u.squares[i].restore(state)
+	}
+
+	state.Turn = u.turn
+	state.SuppressNextSwap = u.suppressNextSwap
+	state.RandSeed = u.randSeed
+	state.HasEnPassant = u.hasEnPassant
+	state.EnPassant = u.enPassant
+	state.WhiteCanCastleKingSide = u.whiteCanCastleKingSide
+	state.WhiteCanCastleQueenSide = u.whiteCanCastleQueenSide
+	state.BlackCanCastleKingSide = u.blackCanCastleKingSide
+	state.BlackCanCastleQueenSide = u.blackCanCastleQueenSide
+	state.HalfmoveClock = u.halfmoveClock
+	state.FullmoveNumber = u.fullmoveNumber
+	state.Ply = u.ply
+	state.positions = state.positions[:u.positions]
+	state.bitboards = u.bitboards
+	state.boardHash = u.boardHash
+}
+
+// begin records the scalar state before a move. It is a no-op on a nil Undo.
+func (u *Undo) begin(state *GameState) {
+	if u == nil {
+		return
+	}
+	*u = Undo{
+		turn:                    state.Turn,
+		suppressNextSwap:        state.SuppressNextSwap,
+		randSeed:                state.RandSeed,
+		hasEnPassant:            state.HasEnPassant,
+		enPassant:               state.EnPassant,
+		whiteCanCastleKingSide:  state.WhiteCanCastleKingSide,
+		whiteCanCastleQueenSide: state.WhiteCanCastleQueenSide,
+		blackCanCastleKingSide:  state.BlackCanCastleKingSide,
+		blackCanCastleQueenSide: state.BlackCanCastleQueenSide,
+		halfmoveClock:           state.HalfmoveClock,
+		fullmoveNumber:          state.FullmoveNumber,
+		ply:                     state.Ply,
+		positions:               len(state.positions),
+		bitboards:               state.bitboards,
+		boardHash:               state.boardHash,
+	}
+}
+
+// save records the square at pos before it changes. It is a no-op on a nil Undo.
+func (u *Undo) save(state *GameState, pos Position) {
+	if u == nil {
+		return
+	}
+	s := savedSquare{piece: state.Board.Squares[pos.File][pos.Rank], sq: uint8(squareIndex(pos))}
+	if s.piece != nil {
+		s.kind, s.color = uint8(s.piece.Kind), uint8(s.piece.Color)
+	}
+	if u.n < len(u.squares) {
+		u.squares[u.n] = s
+		u.n++
+		return
+	}
+	u.overflow = append(u.overflow, s)
+}
+
+func (s savedSquare) restore(state *GameState) {
+	pos := squarePosition(int(s.sq))
+	state.Board.Squares[pos.File][pos.Rank] = s.piece
+	state.placed[pos.File][pos.Rank] = s.piece
+	if s.piece != nil {
+		*s.piece = Piece{Kind: PieceKind(s.kind), Color: Color(s.color)}
+	}
+}
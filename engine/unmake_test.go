@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+// snapshot captures everything UnmakeMove must restore.
+func snapshot(s *GameState) string {
+	return fmt.Sprintf("%s ply=%d hash=%x history=%x", s.FEN(), s.Ply, s.Hash(), s.positions)
+}
+
+func TestUnmakeMoveRestoresPlayouts(t *testing.T) {
+	for name, setup := range map[string]func(*GameState){
+		"classic": func(*GameState) {},
+		"legacy":  func(s *GameState) { s.SwapSource = LegacySource{} },
+		"double":  func(s *GameState) { setRules(s, func(r *Rules) { r.Swaps = 3 }) },
+		"promote": func(s *GameState) {
+			setRules(s, func(r *Rules) {
+				r.BackRankPawns = BackRankPawnsPromote
+				r.SelfCheckSwaps = SelfCheckReroll
+			})
+		},
+	} {
+		s := NewGame()
+		s.RandSeed = 7
+		setup(s)
+		for ply := 0; ply < 120; ply++ {
+			moves := LegalMoves(s)
+			if len(moves) == 0 || GameOutcome(s) != OutcomeNone {
+				break
+			}
+			before := snapshot(s)
+			for _, mv := range moves {
+				_, u, err := MakeMove(s, mv)
+				if err != nil {
+					t.Fatalf("%s ply %d: MakeMove(%s) returned error: %v", name, ply, moveText(mv), err)
+				}
+				UnmakeMove(s, &u)
+				if got := snapshot(s); got != before {
+					t.Fatalf("%s ply %d: unmaking %s left\n%s\nwant\n%s", name, ply, moveText(mv), got, before)
+				}
+			}
+			if _, err := ApplyMoveResult(s, moves[(ply*7)%len(moves)]); err != nil {
+				t.Fatalf("%s ply %d: ApplyMoveResult returned error: %v", name, ply, err)
+			}
+		}
+	}
+}
+
+func TestUnmakeMoveRestoresSpecialMoves(t *testing.T) {
+	for name, tc := range map[string]struct {
+		fen  string
+		move Move
+	}{
+		"castling":   {fen: "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 3 9 - 5", move: Move{From: Position{File: 4, Rank: 0}, To: Position{File: 2, Rank: 0}}},
+		"en passant": {fen: "4k3/8/8/3pP3/8/8/8/4K2N w - d6 0 12 - 5", move: Move{From: Position{File: 4, Rank: 4}, To: Position{File: 3, Rank: 5}}},
+		"promotion":  {fen: "1n2k3/P7/8/8/8/8/8/4K2N w - - 0 30 - 5", move: Move{From: Position{File: 0, Rank: 6}, To: Position{File: 1, Rank: 7}, Promotion: Knight, PromotionSet: true}},
+		"suppressed": {fen: "4k3/8/8/8/8/8/4P3/4K1N1 w - - 5 9 s 1", move: Move{From: Position{File: 6, Rank: 0}, To: Position{File: 5, Rank: 2}}},
+	} {
+		s := mustParseFEN(t, tc.fen)
+		before := snapshot(s)
+		res, u, err := MakeMove(s, tc.move)
+		if err != nil {
+			t.Fatalf("%s: MakeMove returned error: %v", name, err)
+		}
+		if snapshot(s) == before {
+			t.Fatalf("%s: expected the move to change the state", name)
+		}
+		UnmakeMove(s, &u)
+		if got := snapshot(s); got != before {
+			t.Fatalf("%s: unmake left\n%s\nwant\n%s\nafter %+v", name, got, before, res)
+		}
+	}
+}
+
+func TestMakeMoveWithSwapsRollsBackInvalidTargets(t *testing.T) {
+	s := forcedSwapState()
+	before := snapshot(s)
+	bad := Position{File: 3, Rank: 6}
+	if _, _, err := MakeMoveWithSwaps(s, Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}}, []Position{bad}); err == nil {
+		t.Fatalf("expected an error for an opponent swap target")
+	}
+	if got := snapshot(s); got != before {
+		t.Fatalf("expected the state to be rolled back, got\n%s\nwant\n%s", got, before)
+	}
+}
+
+func TestLegalityChecksDoNotAllocate(t *testing.T) {
+	s := NewGame()
+	move := Move{From: Position{File: 4, Rank: 1}, To: Position{File: 4, Rank: 3}}
+	if allocs := testing.AllocsPerRun(100, func() { isLegalMove(s, move) }); allocs != 0 {
+		t.Fatalf("expected isLegalMove not to allocate, got %.1f allocations", allocs)
+	}
+}
+
+func TestMakeUnmakeDoesNotAllocate(t *testing.T) {
+	s, err := ParseFEN(kiwipete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := LegalMoves(s)
+	i := 0
+	allocs := testing.AllocsPerRun(200, func() {
+		_, u, err := MakeMove(s, moves[i%len(moves)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		UnmakeMove(s, &u)
+		GameOutcome(s)
+		i++
+	})
+	if allocs != 0 {
+		t.Fatalf("expected MakeMove, UnmakeMove and GameOutcome not to allocate, got %.1f allocations", allocs)
+	}
+}
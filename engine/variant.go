@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SelfCheckSwapPolicy decides what happens when a swap would leave the
+// mover's own king attacked.
+type SelfCheckSwapPolicy int
+
+const (
+	// SelfCheckAllow keeps such swaps; the opponent may then capture the king,
+	// which ends the game with OutcomeKingCaptured.
+	SelfCheckAllow SelfCheckSwapPolicy = iota
+	// SelfCheckExclude removes self-checking targets from the candidate set.
+	SelfCheckExclude
+	// SelfCheckReroll draws again among the remaining candidates whenever the
+	// drawn target would expose the king. The swap is skipped if none is safe,
+	// and then no draw is made at all: the ply spends no SwapSource draws and,
+	// under LegacySource, does not advance RandSeed, just as when there are no
+	// candidates.
+	SelfCheckReroll
+)
+
+func (p SelfCheckSwapPolicy) String() string {
+	switch p {
+	case SelfCheckExclude:
+		return "exclude"
+	case SelfCheckReroll:
+		return "reroll"
+	default:
+		return "allow"
+	}
+}
+
+// BackRankPawnPolicy decides what happens when a swap would put a pawn on
+// the first or last rank.
+type BackRankPawnPolicy int
+
+const (
+	// BackRankPawnsAllow leaves such pawns as they are. A pawn on its own back
+	// rank may advance one or two squares; a pawn on its last rank is stuck.
+	BackRankPawnsAllow BackRankPawnPolicy = iota
+	// BackRankPawnsExclude removes swap targets that would move a pawn onto
+	// either back rank.
+	BackRankPawnsExclude
+	// BackRankPawnsPromote promotes a pawn swapped onto its last rank to a
+	// queen. Pawns swapped onto their own back rank are treated as in Allow.
+	BackRankPawnsPromote
+)
+
+func (p BackRankPawnPolicy) String() string {
+	switch p {
+	case BackRankPawnsExclude:
+		return "exclude"
+	case BackRankPawnsPromote:
+		return "promote"
+	default:
+		return "allow"
+	}
+}
+
+// CandidateFilter reports whether the piece on from may swap with the piece
+// on candidate. Both squares hold pieces of the same color.
+type CandidateFilter func(state *GameState, from, candidate Position) bool
+
+// SameKindCandidates only lets a piece swap with pieces of its own kind.
+func SameKindCandidates(state *GameState, from, candidate Position) bool {
+	return state.Board.Squares[from.File][from.Rank].Kind == state.Board.Squares[candidate.File][candidate.Rank].Kind
+}
+
+// AdjacentCandidates only lets a piece swap with a piece on a neighboring square.
+func AdjacentCandidates(_ *GameState, from, candidate Position) bool {
+	df := from.File - candidate.File
+	dr := from.Rank - candidate.Rank
+	return df >= -1 && df <= 1 && dr >= -1 && dr <= 1
+}
+
+// Rules holds the variant options of a game. A GameState with nil Rules plays
+// ClassicRules; build other rule sets from ClassicRules or RulesPreset rather
+// than from the zero value, which never swaps.
+type Rules struct {
+	// Name labels the rule set for display, e.g. the preset it came from.
+	Name string
+	// SwapChance is the probability in [0, 1] that an unsuppressed move swaps.
+	SwapChance float64
+	// Swaps is how many swaps follow a move. Each one starts from the square
+	// the moved piece reached with the previous swap.
+	Swaps int
+	// KingSwaps lets kings take part in swaps.
+	KingSwaps bool
+	// SuppressOnCheck skips the swap of a move that gives check.
+	SuppressOnCheck bool
+	// SuppressAfterCheck skips the swap of the reply to a check.
+	SuppressAfterCheck bool
+	// Candidates narrows the swap candidates further; nil allows every piece.
+	Candidates     CandidateFilter
+	SelfCheckSwaps SelfCheckSwapPolicy
+	BackRankPawns  BackRankPawnPolicy
+}
+
+// ClassicRules returns the rules the game has always been played with: one
+// swap after every move, any piece may take part, and no swap on a checking
+// move or on the reply to it.
+func ClassicRules() Rules {
+	return Rules{
+		Name:               "classic",
+		SwapChance:         1,
+		Swaps:              1,
+		KingSwaps:          true,
+		SuppressOnCheck:    true,
+		SuppressAfterCheck: true,
+		SelfCheckSwaps:     SelfCheckAllow,
+		BackRankPawns:      BackRankPawnsAllow,
+	}
+}
+
+var ErrUnknownRules = errors.New("unknown rules preset")
+
+// rulesPresets builds each named preset from the classic rules.
+var rulesPresets = map[string]func(*Rules){
+	"classic": func(*Rules) {},
+	// safe keeps kings out of swaps and never exposes a king or strands a pawn.
+	"safe": func(r *Rules) {
+		r.KingSwaps = false
+		r.SelfCheckSwaps = SelfCheckExclude
+		r.BackRankPawns = BackRankPawnsExclude
+	},
+	"gentle": func(r *Rules) { r.SwapChance = 0.5 },
+	"double": func(r *Rules) { r.Swaps = 2 },
+	"kin":    func(r *Rules) { r.Candidates = SameKindCandidates },
+	"local":  func(r *Rules) { r.Candidates = AdjacentCandidates },
+	// relentless swaps even around checks.
+	"relentless": func(r *Rules) {
+		r.SuppressOnCheck = false
+		r.SuppressAfterCheck = false
+	},
+}
+
+// RulesPreset returns the named rule preset; see RulesPresetNames.
+func RulesPreset(name string) (Rules, error) {
+	apply, ok := rulesPresets[name]
+	if !ok {
+		return Rules{}, fmt.Errorf("%w %q; expected one of %s", ErrUnknownRules, name, strings.Join(RulesPresetNames(), ", "))
+	}
+	rules := ClassicRules()
+	rules.Name = name
+	apply(&rules)
+	return rules, nil
+}
+
+// RulesPresetNames lists the preset names in alphabetical order.
+func RulesPresetNames() []string {
+	names := make([]string, 0, len(rulesPresets))
+	for name := range rulesPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var classicRules = ClassicRules()
+
+// rules returns the state's rules, or the classic rules when none are set.
+func (g *GameState) rules() *Rules {
+	if g.Rules == nil {
+		return &classicRules
+	}
+	return g.Rules
+}
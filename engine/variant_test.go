@@ -0,0 +1,402 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// selfCheckSwapState has white play b1c3 next; swapping the knight with the
+// king on e1 would put the king on c3, in front of the black rook on c8.
+func selfCheckSwapState(t *testing.T, policy SelfCheckSwapPolicy) *GameState {
+	t.Helper()
+	s := mustParseFEN(t, "2r4k/8/8/8/8/8/P7/1N2K3 w - - 0 1 - 1")
+	setRules(s, func(r *Rules) { r.SelfCheckSwaps = policy })
+	return s
+}
+
+var (
+	selfCheckMove = Move{From: Position{File: 1, Rank: 0}, To: Position{File: 2, Rank: 2}} // b1c3
+	kingSquare    = Position{File: 4, Rank: 0}                                             // e1
+	pawnSquare    = Position{File: 0, Rank: 1}                                             // a2
+)
+
+// setRules gives s the classic rules as changed by edit.
+func setRules(s *GameState, edit func(*Rules)) {
+	rules := ClassicRules()
+	edit(&rules)
+	s.Rules = &rules
+}
+
+func TestNilRulesPlayClassicRules(t *testing.T) {
+	s := NewGame()
+	if s.Rules != nil {
+		t.Fatalf("expected NewGame to leave Rules nil, got %+v", s.Rules)
+	}
+	if got := s.rules(); got.Name != "classic" || got.Swaps != 1 || got.SwapChance != 1 || !got.KingSwaps || !got.SuppressOnCheck || !got.SuppressAfterCheck {
+		t.Fatalf("expected classic rules, got %+v", got)
+	}
+
+	setRules(s, func(r *Rules) { r.Swaps = 3 })
+	clone := s.Clone()
+	clone.Rules.Swaps = 1
+	if s.Rules.Swaps != 3 {
+		t.Fatalf("expected Clone to copy the rules, got %d swaps on the original", s.Rules.Swaps)
+	}
+}
+
+func TestSelfCheckAllowLetsOpponentCaptureKing(t *testing.T) {
+	s := selfCheckSwapState(t, SelfCheckAllow)
+	target := kingSquare
+	if _, err := ApplyMoveWithSwap(s, selfCheckMove, &target); err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if !IsInCheck(s, White) {
+		t.Fatalf("expected the swap to expose the white king on c3")
+	}
+
+	res, err := ApplyMoveResult(s, Move{From: Position{File: 2, Rank: 7}, To: Position{File: 2, Rank: 2}}) // c8c3
+	if err != nil {
+		t.Fatalf("expected king capture to be legal, got %v", err)
+	}
+	if !res.HasCapture || res.Captured.Kind != King || res.Swapped || res.Suppression != SuppressedKingCaptured {
+		t.Fatalf("expected an unswapped king capture, got %+v", res)
+	}
+	if got := GameOutcome(s); got != OutcomeKingCaptured {
+		t.Fatalf("expected OutcomeKingCaptured, got %s", got)
+	}
+}
+
+func TestNoMovesAfterKingCapture(t *testing.T) {
+	// the white king is gone, so the black king on h8 is no longer a target
+	s := mustParseFEN(t, "7k/8/8/8/8/8/8/4r2Q w - - 0 2")
+	if got := GameOutcome(s); got != OutcomeKingCaptured {
+		t.Fatalf("expected OutcomeKingCaptured, got %s", got)
+	}
+	if moves := LegalMoves(s); len(moves) != 0 {
+		t.Fatalf("expected no moves without a king, got %v", moves)
+	}
+	if moves := LegalMovesFrom(s, Position{File: 7, Rank: 0}); len(moves) != 0 {
+		t.Fatalf("expected no queen moves without a king, got %v", moves)
+	}
+	if err := ApplyMove(s, Move{From: Position{File: 7, Rank: 0}, To: Position{File: 7, Rank: 7}}); !errors.Is(err, ErrIllegalMove) {
+		t.Fatalf("expected h1h8 to be illegal, got %v", err)
+	}
+}
+
+func TestSelfCheckExcludeDropsUnsafeTargets(t *testing.T) {
+	s := selfCheckSwapState(t, SelfCheckExclude)
+	target := kingSquare
+	if _, err := ApplyMoveWithSwap(s, selfCheckMove, &target); !errors.Is(err, ErrInvalidSwap) {
+		t.Fatalf("expected ErrInvalidSwap for a self-check target, got %v", err)
+	}
+
+	s.SwapSource = ScriptedSource{Picks: map[int][]int{0: {1}}}
+	res, err := ApplyMoveResult(s, selfCheckMove)
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if !res.Swapped || res.SwapB != pawnSquare || IsInCheck(s, White) {
+		t.Fatalf("expected a safe swap with a2, got %+v", res)
+	}
+}
+
+func TestSelfCheckRerollDrawsAgain(t *testing.T) {
+	s := selfCheckSwapState(t, SelfCheckReroll)
+	// the first draw lands on e1 (index 1 of a2, e1); the reroll picks a2
+	s.SwapSource = ScriptedSource{Picks: map[int][]int{0: {1, 0}}}
+	res, err := ApplyMoveResult(s, selfCheckMove)
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if !res.Swapped || res.SwapB != pawnSquare || IsInCheck(s, White) {
+		t.Fatalf("expected the reroll to swap with a2, got %+v", res)
+	}
+
+	// With the pawn gone the only target is unsafe, so no swap happens.
+	s = selfCheckSwapState(t, SelfCheckReroll)
+	s.Board.Squares[0][1] = nil
+	res, err = ApplyMoveResult(s, selfCheckMove)
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if res.Swapped || res.Suppression != SuppressedNoCandidates {
+		t.Fatalf("expected no swap without a safe target, got %+v", res)
+	}
+}
+
+func TestSelfCheckRerollSpendsNoDrawWithoutSafeTarget(t *testing.T) {
+	// under LegacySource every draw advances RandSeed
+	s := selfCheckSwapState(t, SelfCheckReroll)
+	s.SwapSource = LegacySource{}
+	if _, err := ApplyMoveResult(s, selfCheckMove); err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if s.RandSeed == 1 {
+		t.Fatalf("expected the swap draws to advance RandSeed")
+	}
+
+	s = mustParseFEN(t, "2r4k/8/8/8/8/8/8/1N2K3 w - - 0 1 - 1")
+	setRules(s, func(r *Rules) { r.SelfCheckSwaps = SelfCheckReroll })
+	s.SwapSource = LegacySource{}
+	res, err := ApplyMoveResult(s, selfCheckMove)
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if res.Swapped || res.Suppression != SuppressedNoCandidates || s.RandSeed != 1 {
+		t.Fatalf("expected no swap and no draw without a safe target, got %+v and seed %d", res, s.RandSeed)
+	}
+}
+
+// backRankState has white play c2a1 next; swapping the knight with the d4
+// pawn would put the pawn on a1.
+func backRankState(t *testing.T, policy BackRankPawnPolicy) *GameState {
+	t.Helper()
+	s := mustParseFEN(t, "7k/8/8/8/3P4/8/2N5/7K w - - 0 1 - 1")
+	setRules(s, func(r *Rules) { r.BackRankPawns = policy })
+	return s
+}
+
+func TestBackRankPawnsExcludeFiltersCandidates(t *testing.T) {
+	s := backRankState(t, BackRankPawnsExclude)
+	pawn := Position{File: 3, Rank: 3}
+	if _, err := ApplyMoveWithSwap(s, Move{From: Position{File: 2, Rank: 1}, To: Position{File: 0, Rank: 0}}, &pawn); !errors.Is(err, ErrInvalidSwap) {
+		t.Fatalf("expected ErrInvalidSwap for a pawn swapped onto a1, got %v", err)
+	}
+
+	// A moving pawn may not swap with the king on h1 either.
+	s.SuppressNextSwap = true
+	if err := ApplyMove(s, Move{From: pawn, To: Position{File: 3, Rank: 4}}); err != nil {
+		t.Fatalf("ApplyMove returned error: %v", err)
+	}
+	got := SwapCandidates(s, Position{File: 3, Rank: 4})
+	if len(got) != 1 || got[0] != (Position{File: 2, Rank: 1}) {
+		t.Fatalf("expected only the c2 knight as candidate, got %+v", got)
+	}
+}
+
+func TestBackRankPawnsAllowLetsPawnDoubleStepFromFirstRank(t *testing.T) {
+	s := backRankState(t, BackRankPawnsAllow)
+	pawn := Position{File: 3, Rank: 3}
+	if _, err := ApplyMoveWithSwap(s, Move{From: Position{File: 2, Rank: 1}, To: Position{File: 0, Rank: 0}}, &pawn); err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if _, err := ApplyMoveWithSwap(s, Move{From: Position{File: 7, Rank: 7}, To: Position{File: 6, Rank: 7}}, nil); err != nil {
+		t.Fatalf("black reply failed: %v", err)
+	}
+
+	double := Move{From: Position{File: 0, Rank: 0}, To: Position{File: 0, Rank: 2}} // a1a3
+	if !containsMove(LegalMoves(s), double) {
+		t.Fatalf("expected a pawn on its first rank to double-step, got %v", moveKeys(LegalMoves(s)))
+	}
+	s.SuppressNextSwap = true
+	if err := ApplyMove(s, double); err != nil {
+		t.Fatalf("ApplyMove returned error: %v", err)
+	}
+	if !s.HasEnPassant || s.EnPassant != (Position{File: 0, Rank: 1}) {
+		t.Fatalf("expected en passant target a2, got %s", s.FEN())
+	}
+	if _, err := ParseFEN(s.FEN()); err != nil {
+		t.Fatalf("expected FEN with a rank-2 en passant target to parse, got %v", err)
+	}
+}
+
+func TestBackRankDoubleStepGivesCheck(t *testing.T) {
+	s := mustParseFEN(t, "8/8/8/8/4k3/8/8/3P3K w - - 0 1 - 1")
+	res, err := ApplyMoveResult(s, Move{From: Position{File: 3, Rank: 0}, To: Position{File: 3, Rank: 2}}) // d1d3+
+	if err != nil {
+		t.Fatalf("ApplyMoveResult returned error: %v", err)
+	}
+	if !res.GivesCheck || res.Suppression != SuppressedByCheck || !IsInCheck(s, Black) {
+		t.Fatalf("expected d1d3 to give check and suppress the swap, got %+v", res)
+	}
+}
+
+func TestBackRankPawnsPromoteOnLastRank(t *testing.T) {
+	const fen = "8/8/1N6/7k/8/8/P7/7K w - - 0 1 - 1"
+	knight := Move{From: Position{File: 1, Rank: 5}, To: Position{File: 2, Rank: 7}} // b6c8
+	pawn := Position{File: 0, Rank: 1}
+
+	s := mustParseFEN(t, fen)
+	setRules(s, func(r *Rules) { r.BackRankPawns = BackRankPawnsPromote })
+	res, err := ApplyMoveWithSwap(s, knight, &pawn)
+	if err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if !res.SwapPromoted || res.SwapPartner.Kind != Pawn {
+		t.Fatalf("expected the swapped pawn to promote, got %+v", res)
+	}
+	if p := s.Board.Squares[2][7]; p == nil || p.Kind != Queen || p.Color != White {
+		t.Fatalf("expected a white queen on c8, got %+v", p)
+	}
+
+	s = mustParseFEN(t, fen)
+	res, err = ApplyMoveWithSwap(s, knight, &pawn)
+	if err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if res.SwapPromoted {
+		t.Fatalf("expected no promotion under classic rules")
+	}
+	if p := s.Board.Squares[2][7]; p == nil || p.Kind != Pawn {
+		t.Fatalf("expected the pawn to stay a pawn on c8, got %+v", p)
+	}
+	s.Turn = White
+	if moves := LegalMovesFrom(s, Position{File: 2, Rank: 7}); len(moves) != 0 {
+		t.Fatalf("expected a pawn on its last rank to be stuck, got %+v", moves)
+	}
+}
+
+func TestSwapChanceRollsBeforeSwapping(t *testing.T) {
+	move := Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}} // a2a3
+
+	s := forcedSwapState()
+	setRules(s, func(r *Rules) { r.SwapChance = 0 })
+	res, err := ApplyMoveResult(s, move)
+	if err != nil || res.Swapped || res.Suppression != SuppressedByRules {
+		t.Fatalf("expected no swap with zero chance, got %+v, %v", res, err)
+	}
+
+	for name, tc := range map[string]struct {
+		roll    int
+		swapped bool
+	}{
+		"roll succeeds": {roll: 0, swapped: true},
+		"roll fails":    {roll: chanceResolution - 1, swapped: false},
+	} {
+		s := forcedSwapState()
+		setRules(s, func(r *Rules) { r.SwapChance = 0.5 })
+		s.SwapSource = ScriptedSource{Picks: map[int][]int{0: {tc.roll, 0}}}
+		res, err := ApplyMoveResult(s, move)
+		if err != nil {
+			t.Fatalf("%s: ApplyMoveResult returned error: %v", name, err)
+		}
+		if res.Swapped != tc.swapped {
+			t.Fatalf("%s: expected swapped=%v, got %+v", name, tc.swapped, res)
+		}
+		if !tc.swapped && res.Suppression != SuppressedByChance {
+			t.Fatalf("%s: expected SuppressedByChance, got %s", name, res.Suppression)
+		}
+	}
+
+	// Replaying a failed roll needs no target.
+	s = forcedSwapState()
+	setRules(s, func(r *Rules) { r.SwapChance = 0.5 })
+	if res, err := ApplyMoveWithSwap(s, move, nil); err != nil || res.Swapped {
+		t.Fatalf("expected a forced unswapped move, got %+v, %v", res, err)
+	}
+}
+
+func TestMultipleSwapsChainFromTheMovedPiece(t *testing.T) {
+	s := forcedSwapState()
+	setRules(s, func(r *Rules) { r.Swaps = 2 })
+	move := Move{From: Position{File: 0, Rank: 1}, To: Position{File: 0, Rank: 2}} // a2a3
+	knight, pawn := Position{File: 2, Rank: 2}, Position{File: 6, Rank: 1}
+
+	if _, err := ApplyMoveWithSwap(s.Clone(), move, &knight); !errors.Is(err, ErrInvalidSwap) {
+		t.Fatalf("expected ErrInvalidSwap when the second swap is missing, got %v", err)
+	}
+
+	res, err := ApplyMoveWithSwaps(s, move, []Position{knight, pawn})
+	if err != nil {
+		t.Fatalf("ApplyMoveWithSwaps returned error: %v", err)
+	}
+	if len(res.ExtraSwaps) != 1 || res.ExtraSwaps[0].A != knight || res.ExtraSwaps[0].B != pawn {
+		t.Fatalf("expected a second swap c3 <-> g2, got %+v", res.ExtraSwaps)
+	}
+	if res.FinalSquare() != pawn {
+		t.Fatalf("expected the rook to end on g2, got %s", SquareString(res.FinalSquare()))
+	}
+	for sq, kind := range map[Position]PieceKind{{File: 0, Rank: 2}: Knight, knight: Pawn, pawn: Rook} {
+		if p := s.Board.Squares[sq.File][sq.Rank]; p == nil || p.Kind != kind {
+			t.Fatalf("expected %s on %s, got %+v", kind, SquareString(sq), p)
+		}
+	}
+}
+
+func TestKingSwapsCanBeDisabled(t *testing.T) {
+	s := forcedSwapState()
+	setRules(s, func(r *Rules) { r.KingSwaps = false })
+	for _, c := range SwapCandidates(s, Position{File: 0, Rank: 1}) {
+		if c == (Position{File: 4, Rank: 0}) {
+			t.Fatalf("expected the king to be excluded from candidates")
+		}
+	}
+	if got := SwapCandidates(s, Position{File: 4, Rank: 0}); got != nil {
+		t.Fatalf("expected a king to have no candidates, got %+v", got)
+	}
+}
+
+func TestCheckSuppressionCanBeDisabled(t *testing.T) {
+	const fen = "7k/8/8/8/8/8/8/RN2K3 w - - 0 1 - 1"
+	check := Move{From: Position{File: 0, Rank: 0}, To: Position{File: 0, Rank: 7}} // a1a8+
+	knight := Position{File: 1, Rank: 0}
+
+	s := mustParseFEN(t, fen)
+	setRules(s, func(r *Rules) { r.SuppressOnCheck = false })
+	res, err := ApplyMoveWithSwap(s, check, &knight)
+	if err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if !res.GivesCheck || !res.Swapped || !s.SuppressNextSwap {
+		t.Fatalf("expected a swapped check that still suppresses the reply, got %+v", res)
+	}
+
+	s = mustParseFEN(t, fen)
+	setRules(s, func(r *Rules) {
+		r.SuppressOnCheck = false
+		r.SuppressAfterCheck = false
+	})
+	if _, err := ApplyMoveWithSwap(s, check, &knight); err != nil {
+		t.Fatalf("ApplyMoveWithSwap returned error: %v", err)
+	}
+	if s.SuppressNextSwap {
+		t.Fatalf("expected no suppression of the reply")
+	}
+}
+
+func TestCandidateFilters(t *testing.T) {
+	knight := Position{File: 6, Rank: 0} // g1
+	move := Move{From: knight, To: Position{File: 5, Rank: 2}}
+
+	for name, tc := range map[string]struct {
+		filter CandidateFilter
+		want   []string
+	}{
+		"same kind": {filter: SameKindCandidates, want: []string{"b1"}},
+		"adjacent":  {filter: AdjacentCandidates, want: []string{"e2", "f2", "g2"}},
+	} {
+		s := NewGame()
+		setRules(s, func(r *Rules) { r.Candidates = tc.filter })
+		s.SuppressNextSwap = true
+		if err := ApplyMove(s, move); err != nil {
+			t.Fatalf("%s: ApplyMove returned error: %v", name, err)
+		}
+		var got []string
+		for _, c := range SwapCandidates(s, move.To) {
+			got = append(got, SquareString(c))
+		}
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Fatalf("%s: expected candidates %v, got %v", name, tc.want, got)
+		}
+	}
+}
+
+func TestRulesPresets(t *testing.T) {
+	for _, name := range RulesPresetNames() {
+		rules, err := RulesPreset(name)
+		if err != nil {
+			t.Fatalf("RulesPreset(%q) returned error: %v", name, err)
+		}
+		if rules.Name != name {
+			t.Fatalf("expected preset %q to carry its name, got %q", name, rules.Name)
+		}
+	}
+	if _, err := RulesPreset("nope"); !errors.Is(err, ErrUnknownRules) {
+		t.Fatalf("expected ErrUnknownRules, got %v", err)
+	}
+	safe, _ := RulesPreset("safe")
+	if safe.KingSwaps || safe.SelfCheckSwaps != SelfCheckExclude || safe.BackRankPawns != BackRankPawnsExclude {
+		t.Fatalf("unexpected safe preset %+v", safe)
+	}
+}
@@ -0,0 +1,76 @@
+package engine
+
+// Zobrist keys are derived from a fixed seed, so a position hashes to the same
+// value in every process and keys can be stored in books and databases.
+var (
+	zobristPieces      [2][6][64]uint64
+	zobristBlackToMove uint64
+	// zobristCastling is indexed K, Q, k, q.
+	zobristCastling  [4]uint64
+	zobristEnPassant [8]uint64
+	zobristSuppress  uint64
+)
+
+func init() {
+	x := uint64(0x53776170636865) // "Swapche"
+	next := func() uint64 {
+		x++
+		return splitmix64(x)
+	}
+	for c := range zobristPieces {
+		for k := range zobristPieces[c] {
+			for sq := range zobristPieces[c][k] {
+				zobristPieces[c][k][sq] = next()
+			}
+		}
+	}
+	zobristBlackToMove = next()
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+	zobristSuppress = next()
+}
+
+// Hash returns the Zobrist key of the position: piece placement, side to move,
+// castling rights, en passant file and SuppressNextSwap. The move counters,
+// RandSeed and Rules are not part of it. SuppressNextSwap is, because a
+// position where the next reply cannot swap plays differently from the same
+// placement where it can.
+//
+// The placement part is kept up to date by every move and recomputed after
+// pieces were put on or taken off Board.Squares directly; see Board.
+func (g *GameState) Hash() uint64 {
+	g.sync()
+	return g.boardHash ^ g.flagsHash()
+}
+
+// flagsHash hashes the scalar fields covered by Hash. They are cheap enough to
+// hash on demand, which keeps Hash right when callers set them directly.
+func (g *GameState) flagsHash() uint64 {
+	var h uint64
+	if g.Turn == Black {
+		h ^= zobristBlackToMove
+	}
+	for i, ok := range [4]bool{g.WhiteCanCastleKingSide, g.WhiteCanCastleQueenSide, g.BlackCanCastleKingSide, g.BlackCanCastleQueenSide} {
+		if ok {
+			h ^= zobristCastling[i]
+		}
+	}
+	if g.HasEnPassant {
+		h ^= zobristEnPassant[g.EnPassant.File]
+	}
+	if g.SuppressNextSwap {
+		h ^= zobristSuppress
+	}
+	return h
+}
+
+func pieceHash(p *Piece, pos Position) uint64 {
+	if p == nil {
+		return 0
+	}
+	return zobristPieces[p.Color][p.Kind][squareIndex(pos)]
+}
@@ -0,0 +1,103 @@
+package engine
+
+import "testing"
+
+func TestHashIsStable(t *testing.T) {
+	// Stored keys must keep matching, so the key tables may never change.
+	if got := NewGame().Hash(); got != 0x288323279922961e {
+		t.Fatalf("start position hash changed to %#x", got)
+	}
+	if got, want := mustParseFEN(t, StartFEN).Hash(), NewGame().Hash(); got != want {
+		t.Fatalf("ParseFEN(StartFEN).Hash() = %#x, want %#x", got, want)
+	}
+}
+
+func TestHashTracksMoves(t *testing.T) {
+	for name, setup := range map[string]func(*GameState){
+		"classic": func(*GameState) {},
+		"double":  func(s *GameState) { setRules(s, func(r *Rules) { r.Swaps = 3 }) },
+		"promote": func(s *GameState) {
+			setRules(s, func(r *Rules) { r.BackRankPawns = BackRankPawnsPromote })
+		},
+	} {
+		s := NewGame()
+		s.RandSeed = 11
+		setup(s)
+		for ply := 0; ply < 150; ply++ {
+			moves := LegalMoves(s)
+			if len(moves) == 0 || GameOutcome(s) != OutcomeNone {
+				break
+			}
+			if _, err := ApplyMoveResult(s, moves[(ply*3)%len(moves)]); err != nil {
+				t.Fatalf("%s ply %d: ApplyMoveResult returned error: %v", name, ply, err)
+			}
+			if got, want := s.Hash(), mustParseFEN(t, s.FEN()).Hash(); got != want {
+				t.Fatalf("%s ply %d: incremental hash %#x, recomputed %#x for %s", name, ply, got, want, s.FEN())
+			}
+		}
+	}
+}
+
+func TestHashCoversSwapchessState(t *testing.T) {
+	base := mustParseFEN(t, "r3k2r/8/8/3pP3/8/8/8/R3K2R w KQkq d6 0 1 - 1")
+	seen := map[uint64]string{base.Hash(): "base"}
+	for name, edit := range map[string]func(*GameState){
+		"side":       func(s *GameState) { s.Turn = Black },
+		"castling":   func(s *GameState) { s.BlackCanCastleQueenSide = false },
+		"en passant": func(s *GameState) { s.HasEnPassant = false },
+		"suppress":   func(s *GameState) { s.SuppressNextSwap = true },
+		"placement": func(s *GameState) {
+			s.Board.Squares[0][0], s.Board.Squares[0][1] = nil, s.Board.Squares[0][0]
+			s.Rehash()
+		},
+	} {
+		s := base.Clone()
+		edit(s)
+		if prev, ok := seen[s.Hash()]; ok {
+			t.Fatalf("%s: hash %#x collides with %s", name, s.Hash(), prev)
+		}
+		seen[s.Hash()] = name
+	}
+
+	// counters and the seed do not identify a position
+	s := base.Clone()
+	s.HalfmoveClock, s.FullmoveNumber, s.RandSeed = 7, 30, 99
+	if s.Hash() != base.Hash() {
+		t.Fatalf("expected counters and seed to leave the hash unchanged")
+	}
+}
+
+func TestHashMatchesTranspositions(t *testing.T) {
+	a := NewGame()
+	applyUnswapped(t, a, "g1f3", "g8f6", "b1c3")
+	b := NewGame()
+	applyUnswapped(t, b, "b1c3", "g8f6", "g1f3")
+	if a.Hash() != b.Hash() {
+		t.Fatalf("expected transposed move orders to hash alike:\n%s\n%s", a.FEN(), b.FEN())
+	}
+}
+
+func TestHashFollowsDirectBoardEdits(t *testing.T) {
+	// boards built square by square, as the older tests do
+	s := &GameState{Turn: White}
+	s.Board.Squares[4][0] = &Piece{Kind: King, Color: White}
+	s.Board.Squares[0][1] = &Piece{Kind: Rook, Color: White}
+	s.Board.Squares[4][7] = &Piece{Kind: King, Color: Black}
+	if got, want := s.Hash(), mustParseFEN(t, "4k3/8/8/8/8/8/R7/4K3 w - - 0 1").Hash(); got != want {
+		t.Fatalf("expected a built board to hash like its FEN, got %#x want %#x", got, want)
+	}
+
+	// an edit between moves must not leave a stale key in the history
+	s = NewGame()
+	applyUnswapped(t, s, "g1f3", "g8f6")
+	s.Board.Squares[0][1] = nil
+	applyUnswapped(t, s, "f3g1", "f6g8", "g1f3", "g8f6")
+	// applyUnswapped recorded every position with the flag set
+	s.SuppressNextSwap = true
+	if got := RepetitionCount(s); got != 2 {
+		t.Fatalf("expected the edited position to repeat twice, got %d", got)
+	}
+	if got, want := s.Hash(), mustParseFEN(t, s.FEN()).Hash(); got != want {
+		t.Fatalf("expected the edited board to hash like its FEN, got %#x want %#x", got, want)
+	}
+}
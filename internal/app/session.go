@@ -1,10 +1,15 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/engine/bot"
+	"github.com/divijg19/Swapchess/internal/uci"
 	"github.com/divijg19/Swapchess/view"
 )
 
@@ -23,6 +28,43 @@ const (
 	InputModeBoardSelect InputMode = "board_select"
 )
 
+// Player says who moves a side.
+type Player string
+
+const (
+	PlayerHuman Player = "human"
+	PlayerBot   Player = "bot"
+)
+
+// playerExec prefixes the command of an external engine player, as in
+// "exec:/path/to/engine".
+const playerExec = "exec:"
+
+// ParsePlayer parses a --white or --black value; empty means human.
+func ParsePlayer(raw string) (Player, error) {
+	value := strings.TrimSpace(raw)
+	if strings.HasPrefix(strings.ToLower(value), playerExec) {
+		command := strings.TrimSpace(value[len(playerExec):])
+		if command == "" {
+			return "", fmt.Errorf("player %q has no engine command", raw)
+		}
+		return Player(playerExec + command), nil
+	}
+	switch Player(strings.ToLower(value)) {
+	case "", PlayerHuman:
+		return PlayerHuman, nil
+	case PlayerBot:
+		return PlayerBot, nil
+	default:
+		return "", fmt.Errorf("unknown player %q; expected human, bot or exec:COMMAND", raw)
+	}
+}
+
+// ExecCommand returns the engine command of an exec: player.
+func (p Player) ExecCommand() (string, bool) {
+	return strings.CutPrefix(string(p), playerExec)
+}
+
 type RendererMode string
 
 const (
@@ -44,6 +86,7 @@ type MoveRecord struct {
 	Move      engine.Move
 	Notation  string
 	SwapEvent *view.SwapEvent
+	Result    engine.MoveResult
 }
 
 type Session struct {
@@ -61,7 +104,23 @@ type Session struct {
 
 	DebugRendererEnabled bool
 
-	history        []*engine.GameState
+	// AsyncBots leaves bot moves to StartBotSearch instead of playing them
+	// within each action, for UIs that search in the background.
+	AsyncBots bool
+
+	// players and bots hold who plays each color, and the bot that does or
+	// nil for a human.
+	players [2]Player
+	bots    [2]bot.Bot
+	// builtin is Options.Bot, or defaultBot without one.
+	builtin bot.Bot
+	// search is the bot search in progress when AsyncBots is set.
+	search      *BotSearch
+	goRequested bool
+	// botFailed is set when a search failed, so that searches do not restart
+	// on their own until go, a move or an undo.
+	botFailed      bool
+	history        []engine.Undo
 	pendingMove    engine.Move
 	hasPendingMove bool
 	lastMove       *engine.Move
@@ -73,13 +132,104 @@ const (
 	promotionPlaceholder     = "promotion: q/r/b/n"
 )
 
+// Options configures a new Session.
+type Options struct {
+	DebugRenderer string
+	// FEN optionally sets the starting position; empty means the standard start.
+	FEN string
+	// Seed replaces the starting RandSeed when SeedSet is true.
+	Seed    int64
+	SeedSet bool
+	// Rules names an engine.RulesPreset; empty means the classic rules.
+	Rules string
+	// White and Black say who plays each side; empty means human.
+	White Player
+	Black Player
+	// AsyncBots sets Session.AsyncBots.
+	AsyncBots bool
+	// Bot plays the bot sides, and the human sides on the go command; nil
+	// means an Expectimax bot that thinks botMoveTime per move.
+	Bot bot.Bot
+}
+
+// botMoveTime is how long the bot thinks about each move.
+const botMoveTime = 500 * time.Millisecond
+
+// defaultBot plays for sessions whose Options leave Bot nil.
+var defaultBot bot.Bot = bot.Expectimax{MoveTime: botMoveTime}
+
+// engineTimeout is how long an exec: engine may take to start, and to answer
+// beyond botMoveTime.
+var engineTimeout = uci.DefaultEngineTimeout
+
+// moveListener is a bot that follows every move of the game, such as an
+// external engine that cannot work out the swaps itself.
+type moveListener interface {
+	Played(res engine.MoveResult, state *engine.GameState)
+}
+
+// BotSearch is a bot move being searched on a copy of the game, so that a UI
+// can stay responsive while the bot thinks and cancel the search. See
+// Session.StartBotSearch.
+type BotSearch struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	game   *engine.GameState
+	player bot.Bot
+}
+
+// Run searches for the move. It may be called from another goroutine while
+// the session goes on; pass its result to Session.FinishBotSearch.
+func (b *BotSearch) Run() BotMove {
+	move, err := bot.ChooseMoveContext(b.ctx, b.player, b.game)
+	return BotMove{search: b, Move: move, Err: err}
+}
+
+// BotMove is the outcome of a BotSearch.
+type BotMove struct {
+	search *BotSearch
+	Move   engine.Move
+	Err    error
+}
+
 func NewSession(debugRenderer string) *Session {
+	session, _ := NewSessionWithOptions(Options{DebugRenderer: debugRenderer})
+	return session
+}
+
+// NewSessionWithOptions creates a session from opts, failing if the starting FEN is invalid.
+func NewSessionWithOptions(opts Options) (*Session, error) {
+	game := engine.NewGame()
+	if strings.TrimSpace(opts.FEN) != "" {
+		parsed, err := engine.ParseFEN(opts.FEN)
+		if err != nil {
+			return nil, err
+		}
+		game = parsed
+	}
+	if opts.SeedSet {
+		game.RandSeed = opts.Seed
+	}
+	if strings.TrimSpace(opts.Rules) != "" {
+		rules, err := engine.RulesPreset(strings.TrimSpace(opts.Rules))
+		if err != nil {
+			return nil, err
+		}
+		game.Rules = &rules
+	}
+
+	debugRenderer := opts.DebugRenderer
 	session := &Session{
-		Game:      engine.NewGame(),
+		Game:      game,
 		InputMode: InputModeCommand,
 		Renderer:  RendererView,
 		Cursor:    engine.Position{File: 4, Rank: 1},
 		Message:   "Enter a move like e2e4. Type help for commands.",
+		AsyncBots: opts.AsyncBots,
+		builtin:   opts.Bot,
+	}
+	if session.builtin == nil {
+		session.builtin = defaultBot
 	}
 
 	switch RendererMode(strings.ToLower(strings.TrimSpace(debugRenderer))) {
@@ -92,9 +242,41 @@ func NewSession(debugRenderer string) *Session {
 		}
 	}
 
+	for color, player := range [2]Player{opts.White, opts.Black} {
+		parsed, err := ParsePlayer(string(player))
+		if err != nil {
+			session.Close()
+			return nil, err
+		}
+		session.players[color] = parsed
+		if parsed == PlayerBot {
+			session.bots[color] = session.builtin
+		}
+		if command, ok := parsed.ExecCommand(); ok {
+			rules := engine.ClassicRules()
+			if game.Rules != nil {
+				rules = *game.Rules
+			}
+			e, err := uci.StartEngine(uci.EngineOptions{Command: command, Rules: rules, MoveTime: botMoveTime, Timeout: engineTimeout})
+			if err != nil {
+				session.Close()
+				return nil, fmt.Errorf("%s engine: %w", engine.Color(color), err)
+			}
+			session.bots[color] = e
+		}
+	}
+
 	session.refreshView()
+	switch replies := session.playBotReplies(); {
+	case len(replies) == 0:
+	case session.AsyncBots:
+		// the bot is thinking
+		session.Message = replies[0]
+	default:
+		session.Message = strings.Join(replies, ". ") + ". Your move."
+	}
 	session.Hint = session.Preview("")
-	return session
+	return session, nil
 }
 
 func (s *Session) Resize(width, height int) {
@@ -154,7 +336,26 @@ func (s *Session) Submit(raw string) ActionResult {
 		s.Message = "Move log cleared."
 		s.Hint = s.Preview("")
 		return s.result(false, true)
+	case "fen":
+		s.Message = "FEN: " + s.Game.FEN()
+		s.Hint = s.Preview("")
+		return s.result(false, true)
+	case "seed":
+		s.Message = fmt.Sprintf("Seed: %d", s.Game.RandSeed)
+		s.Hint = s.Preview("")
+		return s.result(false, true)
+	case "rules":
+		s.Message = "Rules: " + s.RulesName()
+		s.Hint = s.Preview("")
+		return s.result(false, true)
+	case "players":
+		s.Message = fmt.Sprintf("White: %s, Black: %s", s.PlayerFor(engine.White), s.PlayerFor(engine.Black))
+		s.Hint = s.Preview("")
+		return s.result(false, true)
+	case "go":
+		return s.playBotTurn()
 	case "quit", "exit":
+		s.CancelBotSearch()
 		s.Message = "Quitting."
 		s.Hint = s.Preview("")
 		return s.result(true, true)
@@ -275,6 +476,11 @@ func (s *Session) HelpLines() []string {
 		"help",
 		"undo",
 		"clear",
+		"fen",
+		"seed",
+		"rules",
+		"players",
+		"go",
 		"quit",
 		"move e2e4",
 		"promotion e7e8q",
@@ -285,6 +491,35 @@ func (s *Session) HelpLines() []string {
 	return lines
 }
 
+// PlayerFor reports who plays color.
+func (s *Session) PlayerFor(color engine.Color) Player {
+	if s.players[color] == "" {
+		return PlayerHuman
+	}
+	return s.players[color]
+}
+
+// Close stops the bot search in progress and the external engines.
+func (s *Session) Close() error {
+	s.CancelBotSearch()
+	var errs []error
+	for color, b := range s.bots {
+		if e, ok := b.(*uci.Engine); ok {
+			errs = append(errs, e.Close())
+			s.bots[color] = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RulesName names the rule set the game is played with.
+func (s *Session) RulesName() string {
+	if s.Game.Rules == nil || s.Game.Rules.Name == "" {
+		return engine.ClassicRules().Name
+	}
+	return s.Game.Rules.Name
+}
+
 func (s *Session) LastMoveNotation() string {
 	if s.lastMove == nil {
 		return "-"
@@ -352,6 +587,16 @@ func (s *Session) preview(raw string) string {
 }
 
 func (s *Session) submitMove(move engine.Move) ActionResult {
+	if s.View.Status.IsGameOver() {
+		s.Message = "Game over: " + s.View.Status.String() + ". Undo to keep playing."
+		s.Hint = s.Preview("")
+		return s.result(false, false)
+	}
+	if s.search != nil {
+		s.Message = s.Game.Turn.String() + " bot is thinking. Undo to take back your move."
+		s.Hint = s.Preview("")
+		return s.result(false, false)
+	}
 	if err := validateMoveContext(s.Game, move); err != nil {
 		s.Message = "Invalid move context: " + err.Error()
 		s.Hint = s.Preview("")
@@ -376,25 +621,207 @@ func (s *Session) submitMove(move engine.Move) ActionResult {
 }
 
 func (s *Session) applyMove(move engine.Move) ActionResult {
-	previous := s.Game.Clone()
-	movedPiece := s.Game.Board.Squares[move.From.File][move.From.Rank]
-	mover := s.Game.Turn
-
-	if err := engine.ApplyMove(s.Game, move); err != nil {
+	text, err := s.playMove(move)
+	if err != nil {
 		s.Message = "Illegal move: " + err.Error()
 		s.Hint = s.Preview("")
 		return s.result(false, false)
 	}
 
-	s.history = append(s.history, previous)
+	s.Message = "Move applied: " + text
+	if replies := s.playBotReplies(); len(replies) > 0 {
+		s.Message += ". " + strings.Join(replies, ". ")
+	}
+	if s.View.Status.IsGameOver() {
+		s.Message += ". Game over: " + s.View.Status.String() + "."
+	}
+	s.Hint = s.Preview("")
+	return s.result(false, true)
+}
+
+// playBotTurn lets a bot play the side to move, whoever controls it.
+func (s *Session) playBotTurn() ActionResult {
+	if s.View.Status.IsGameOver() {
+		s.Message = "Game over: " + s.View.Status.String() + ". Undo to keep playing."
+		s.Hint = s.Preview("")
+		return s.result(false, false)
+	}
+	if s.AsyncBots {
+		if s.search == nil {
+			s.goRequested = true
+		}
+		s.Message = s.Game.Turn.String() + " bot is thinking..."
+		s.Hint = s.Preview("")
+		return s.result(false, true)
+	}
+	text, err := s.playBotMove()
+	if err != nil {
+		s.Message = "Bot failed: " + err.Error()
+		s.Hint = s.Preview("")
+		return s.result(false, false)
+	}
+	s.Message = text
+	if replies := s.playBotReplies(); len(replies) > 0 {
+		s.Message += ". " + strings.Join(replies, ". ")
+	}
+	if s.View.Status.IsGameOver() {
+		s.Message += ". Game over: " + s.View.Status.String() + "."
+	}
+	s.Hint = s.Preview("")
+	return s.result(false, true)
+}
+
+// playBotReplies plays the bot's moves while a bot is to move against a
+// human. Games between two bots advance one move per go command instead.
+// With AsyncBots it only reports that the bot is thinking and leaves the
+// move to StartBotSearch, which also plays games between two bots through.
+func (s *Session) playBotReplies() []string {
+	if s.AsyncBots {
+		if !s.View.Status.IsGameOver() && s.botToMove() {
+			return []string{s.Game.Turn.String() + " bot is thinking..."}
+		}
+		return nil
+	}
+	var replies []string
+	for !s.View.Status.IsGameOver() && s.botToReply() {
+		text, err := s.playBotMove()
+		if err != nil {
+			replies = append(replies, "Bot failed: "+err.Error())
+			break
+		}
+		replies = append(replies, text)
+	}
+	return replies
+}
+
+// botToReply reports whether a bot is to move against a human.
+func (s *Session) botToReply() bool {
+	turn := s.Game.Turn
+	return s.bots[turn] != nil && s.bots[1-turn] == nil
+}
+
+// botToMove reports whether a bot plays the side to move without waiting for
+// go. With AsyncBots that is any bot, so that searches chain through games
+// between two bots; the synchronous CLI only lets a bot reply to a human and
+// steps bot games one move per go, since it would otherwise block until the
+// game ends.
+func (s *Session) botToMove() bool {
+	if s.AsyncBots {
+		return s.bots[s.Game.Turn] != nil
+	}
+	return s.botToReply()
+}
+
+// botFor returns the bot that moves for color, using builtin for
+// human sides.
+func (s *Session) botFor(color engine.Color) bot.Bot {
+	if s.bots[color] != nil {
+		return s.bots[color]
+	}
+	return s.builtin
+}
+
+// playBotMove plays one bot move for the side to move and describes it.
+func (s *Session) playBotMove() (string, error) {
+	color := s.Game.Turn
+	move, err := s.botFor(color).ChooseMove(s.Game)
+	if err != nil {
+		return "", err
+	}
+	text, err := s.playMove(move)
+	if err != nil {
+		return "", err
+	}
+	return color.String() + " bot played " + text, nil
+}
+
+// StartBotSearch starts searching for the next bot move when AsyncBots is
+// set and a bot is to move or the go command asked for one. It returns nil
+// when there is nothing to search or a search is already running. The caller
+// runs the search and hands its result to FinishBotSearch.
+func (s *Session) StartBotSearch() *BotSearch {
+	wanted := s.goRequested || s.botToMove() && !s.botFailed
+	s.goRequested = false
+	if !s.AsyncBots || s.search != nil || s.View.Status.IsGameOver() || !wanted {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.search = &BotSearch{ctx: ctx, cancel: cancel, game: s.Game.Clone(), player: s.botFor(s.Game.Turn)}
+	return s.search
+}
+
+// FinishBotSearch plays the move a search found. Results of searches that
+// were cancelled, or have been replaced by another, are ignored.
+func (s *Session) FinishBotSearch(res BotMove) ActionResult {
+	if res.search == nil || res.search != s.search {
+		return s.result(false, false)
+	}
+	s.search = nil
+	res.search.cancel()
+
+	color := s.Game.Turn
+	text := ""
+	err := res.Err
+	if err == nil {
+		text, err = s.playMove(res.Move)
+	}
+	if err != nil {
+		// a crashed or confused engine would most likely fail again at once
+		s.botFailed = true
+		s.Message = "Bot failed: " + err.Error()
+		s.Hint = s.Preview("")
+		return s.result(false, false)
+	}
+	s.Message = color.String() + " bot played " + text
+	if s.View.Status.IsGameOver() {
+		s.Message += ". Game over: " + s.View.Status.String() + "."
+	} else if s.botToMove() {
+		s.Message += ". " + s.Game.Turn.String() + " bot is thinking..."
+	}
+	s.Hint = s.Preview("")
+	return s.result(false, true)
+}
+
+// CancelBotSearch stops the bot search in progress, if any.
+func (s *Session) CancelBotSearch() {
+	if s.search != nil {
+		s.search.cancel()
+		s.search = nil
+	}
+	s.goRequested = false
+}
 
-	swapEvent := detectSwapEvent(s.Game, movedPiece, move.To)
+// BotThinking reports whether a bot search is in progress.
+func (s *Session) BotThinking() bool {
+	return s.search != nil
+}
+
+// playMove applies move, records it for the log and undo, and describes it
+// with its swaps, e.g. "e2e4 (swap e4 <-> d1)".
+func (s *Session) playMove(move engine.Move) (string, error) {
+	mover := s.Game.Turn
+
+	moveResult, undo, err := engine.MakeMove(s.Game, move)
+	if err != nil {
+		return "", err
+	}
+
+	s.history = append(s.history, undo)
+	s.botFailed = false
+	for _, b := range s.bots {
+		if l, ok := b.(moveListener); ok {
+			l.Played(moveResult, s.Game)
+		}
+	}
+
+	swapEvent := swapEventFromResult(moveResult)
 	record := MoveRecord{
 		Index:     len(s.MoveLog) + 1,
 		Player:    mover,
 		Move:      move,
 		Notation:  MoveString(move),
 		SwapEvent: cloneSwapEvent(swapEvent),
+		Result:    moveResult,
 	}
 	s.MoveLog = append(s.MoveLog, record)
 
@@ -408,26 +835,38 @@ func (s *Session) applyMove(move engine.Move) ActionResult {
 	s.refreshView()
 
 	if swapEvent != nil {
-		s.Message = fmt.Sprintf("Move applied: %s (swap %s <-> %s)", record.Notation, PositionString(swapEvent.A), PositionString(swapEvent.B))
-	} else {
-		s.Message = "Move applied: " + record.Notation
+		swaps := []string{PositionString(swapEvent.A) + " <-> " + PositionString(swapEvent.B)}
+		for _, step := range moveResult.ExtraSwaps {
+			swaps = append(swaps, PositionString(step.A)+" <-> "+PositionString(step.B))
+		}
+		text := fmt.Sprintf("%s (swap %s", record.Notation, strings.Join(swaps, ", "))
+		if moveResult.SwapPromoted {
+			text += ", pawn promoted"
+		}
+		return text + ")", nil
 	}
-	s.Hint = s.Preview("")
-	return s.result(false, true)
+	if moveResult.Suppression == engine.SuppressedByCheck || moveResult.Suppression == engine.SuppressedAfterCheck ||
+		moveResult.Suppression == engine.SuppressedByChance {
+		return fmt.Sprintf("%s (no swap: %s)", record.Notation, moveResult.Suppression), nil
+	}
+	return record.Notation, nil
 }
 
 func (s *Session) undo() ActionResult {
+	s.CancelBotSearch()
+	s.botFailed = false
 	if len(s.history) == 0 {
 		s.Message = "No moves to undo."
 		s.Hint = s.Preview("")
 		return s.result(false, false)
 	}
 
-	last := s.history[len(s.history)-1]
-	s.history = s.history[:len(s.history)-1]
-	s.Game = last
-	if len(s.MoveLog) > 0 {
-		s.MoveLog = s.MoveLog[:len(s.MoveLog)-1]
+	s.undoOne()
+	undone := 1
+	// take back the bot's reply together with the human move it answered
+	for len(s.history) > 0 && s.botToReply() {
+		s.undoOne()
+		undone++
 	}
 
 	if len(s.MoveLog) > 0 {
@@ -446,10 +885,21 @@ func (s *Session) undo() ActionResult {
 	s.pendingMove = engine.Move{}
 	s.refreshView()
 	s.Message = "Undid last move."
+	if undone > 1 {
+		s.Message = fmt.Sprintf("Undid last %d moves.", undone)
+	}
 	s.Hint = s.Preview("")
 	return s.result(false, true)
 }
 
+func (s *Session) undoOne() {
+	engine.UnmakeMove(s.Game, &s.history[len(s.history)-1])
+	s.history = s.history[:len(s.history)-1]
+	if len(s.MoveLog) > 0 {
+		s.MoveLog = s.MoveLog[:len(s.MoveLog)-1]
+	}
+}
+
 func (s *Session) setRenderer(mode RendererMode) ActionResult {
 	if !s.DebugRendererEnabled {
 		s.Message = "Renderer commands are disabled unless launched with --debug-renderer."
@@ -630,7 +1080,7 @@ func normalizeCommand(raw string) string {
 
 func recognizedCommand(command string, debugEnabled bool) bool {
 	switch command {
-	case "help", "?", "undo", "u", "clear", "quit", "exit":
+	case "help", "?", "undo", "u", "clear", "fen", "seed", "rules", "players", "go", "quit", "exit":
 		return true
 	case "renderer view", "render view", "view",
 		"renderer engine", "render engine", "engine",
@@ -641,24 +1091,11 @@ func recognizedCommand(command string, debugEnabled bool) bool {
 	}
 }
 
-func detectSwapEvent(state *engine.GameState, movedPiece *engine.Piece, destination engine.Position) *view.SwapEvent {
-	if movedPiece == nil {
+func swapEventFromResult(result engine.MoveResult) *view.SwapEvent {
+	if !result.Swapped {
 		return nil
 	}
-
-	for file := 0; file < 8; file++ {
-		for rank := 0; rank < 8; rank++ {
-			if state.Board.Squares[file][rank] == movedPiece {
-				finalPosition := engine.Position{File: file, Rank: rank}
-				if finalPosition == destination {
-					return nil
-				}
-				return &view.SwapEvent{A: destination, B: finalPosition}
-			}
-		}
-	}
-
-	return nil
+	return &view.SwapEvent{A: result.SwapA, B: result.SwapB}
 }
 
 func cloneSwapEvent(event *view.SwapEvent) *view.SwapEvent {
@@ -1,12 +1,21 @@
 package app
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/engine/bot"
+	"github.com/divijg19/Swapchess/internal/uci/ucitest"
 )
 
+// testBot stands in for the default bot, which thinks for half a second.
+var testBot = bot.Expectimax{Depth: 1}
+
 func TestParseMoveNormalizesInput(t *testing.T) {
 	move, err := parseMove(" E2 -> E4 ")
 	if err != nil {
@@ -97,6 +106,9 @@ func TestSubmitLegalMoveAndUndo(t *testing.T) {
 	if session.Game.Turn != engine.White {
 		t.Fatalf("expected white to move after undo")
 	}
+	if got := session.Game.FEN(); got != engine.StartFEN {
+		t.Fatalf("expected undo to restore the start position, got %q", got)
+	}
 	if len(session.MoveLog) != 0 {
 		t.Fatalf("expected move log to be empty after undo")
 	}
@@ -323,3 +335,396 @@ func swapReadySession() *Session {
 	session.Hint = session.Preview("")
 	return session
 }
+
+func TestNewSessionWithOptionsStartsFromFEN(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/4P3/4K3 b - - 3 12 s 9"
+	session, err := NewSessionWithOptions(Options{FEN: fen})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if session.Game.Turn != engine.Black || !session.View.SuppressNextSwap {
+		t.Fatalf("expected FEN state in session, got turn %s suppress %v", session.Game.Turn, session.View.SuppressNextSwap)
+	}
+
+	session.Submit("fen")
+	if session.Message != "FEN: "+fen {
+		t.Fatalf("unexpected fen command message: %q", session.Message)
+	}
+
+	if _, err := NewSessionWithOptions(Options{FEN: "8/8 w"}); err == nil {
+		t.Fatalf("expected error for invalid FEN")
+	}
+}
+
+func TestNewSessionWithOptionsAppliesSeed(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Seed: 987, SeedSet: true})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if session.Game.RandSeed != 987 || session.View.Seed != 987 {
+		t.Fatalf("expected seed 987, got game %d view %d", session.Game.RandSeed, session.View.Seed)
+	}
+
+	session.Submit("seed")
+	if session.Message != "Seed: 987" {
+		t.Fatalf("unexpected seed command message: %q", session.Message)
+	}
+	if !strings.HasSuffix(session.Game.FEN(), " 987") {
+		t.Fatalf("expected exported FEN to carry the seed, got %q", session.Game.FEN())
+	}
+
+	fromFEN, err := NewSessionWithOptions(Options{FEN: "4k3/8/8/8/8/8/8/4K3 w - - 0 1 - 5", Seed: 6, SeedSet: true})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if fromFEN.Game.RandSeed != 6 {
+		t.Fatalf("expected explicit seed to override the FEN seed, got %d", fromFEN.Game.RandSeed)
+	}
+}
+
+func TestNewSessionWithOptionsAppliesRulesPreset(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Rules: "double"})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if session.Game.Rules == nil || session.Game.Rules.Swaps != 2 {
+		t.Fatalf("expected the double preset, got %+v", session.Game.Rules)
+	}
+	session.Submit("rules")
+	if session.Message != "Rules: double" {
+		t.Fatalf("unexpected rules command message: %q", session.Message)
+	}
+
+	session.Submit("g1f3")
+	if strings.Count(session.Message, "<->") != 2 {
+		t.Fatalf("expected both swaps in the move message, got %q", session.Message)
+	}
+
+	if _, err := NewSessionWithOptions(Options{Rules: "nope"}); !errors.Is(err, engine.ErrUnknownRules) {
+		t.Fatalf("expected ErrUnknownRules, got %v", err)
+	}
+}
+
+func TestSessionAnnouncesDrawAndBlocksMoves(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{FEN: "4k3/8/8/8/8/8/8/R3K3 w - - 99 80 s 1"})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+
+	session.Submit("a1a2")
+	if !strings.Contains(session.Message, "Game over: draw (50 moves).") {
+		t.Fatalf("expected draw announcement, got %q", session.Message)
+	}
+
+	session.Submit("e8d8")
+	if !strings.HasPrefix(session.Message, "Game over: draw (50 moves).") {
+		t.Fatalf("expected moves to be blocked after a draw, got %q", session.Message)
+	}
+	if len(session.MoveLog) != 1 {
+		t.Fatalf("expected no further moves after the draw, got %d", len(session.MoveLog))
+	}
+
+	session.Submit("undo")
+	if session.View.Status.IsGameOver() {
+		t.Fatalf("expected undo to reopen the game, got %s", session.View.Status)
+	}
+}
+
+func TestMoveRecordCarriesMoveResult(t *testing.T) {
+	session := swapReadySession()
+	session.Submit("a2a3")
+
+	result := session.MoveLog[0].Result
+	if !result.Swapped {
+		t.Fatalf("expected swapped move result, got %+v", result)
+	}
+	event := session.MoveLog[0].SwapEvent
+	if event.A != result.SwapA || event.B != result.SwapB {
+		t.Fatalf("expected swap event %+v to match result squares %+v/%+v", event, result.SwapA, result.SwapB)
+	}
+	rook := session.Game.Board.Squares[result.SwapB.File][result.SwapB.Rank]
+	if rook == nil || rook.Kind != engine.Rook {
+		t.Fatalf("expected moved rook on %s, got %+v", PositionString(result.SwapB), rook)
+	}
+}
+
+func TestSessionBotRepliesToHumanMoves(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Bot: testBot, Black: PlayerBot})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if len(session.MoveLog) != 0 {
+		t.Fatalf("expected the bot to wait for white, got %d moves", len(session.MoveLog))
+	}
+
+	session.Submit("e2e4")
+	if len(session.MoveLog) != 2 || session.MoveLog[1].Player != engine.Black {
+		t.Fatalf("expected a black bot reply, got %d moves", len(session.MoveLog))
+	}
+	if !strings.HasPrefix(session.Message, "Move applied: e2e4") || !strings.Contains(session.Message, "Black bot played ") {
+		t.Fatalf("expected both moves in the message, got %q", session.Message)
+	}
+	if session.Game.Turn != engine.White {
+		t.Fatalf("expected white to move after the reply")
+	}
+
+	session.Submit("undo")
+	if len(session.MoveLog) != 0 || session.Game.FEN() != engine.StartFEN {
+		t.Fatalf("expected undo to take back the reply and the move, got %d moves at %s", len(session.MoveLog), session.Game.FEN())
+	}
+	if session.Message != "Undid last 2 moves." {
+		t.Fatalf("unexpected undo message %q", session.Message)
+	}
+}
+
+func TestSessionBotOpensAsWhite(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Bot: testBot, White: PlayerBot})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if len(session.MoveLog) != 1 || session.Game.Turn != engine.Black {
+		t.Fatalf("expected the white bot to open, got %d moves", len(session.MoveLog))
+	}
+	if !strings.HasPrefix(session.Message, "White bot played ") {
+		t.Fatalf("unexpected opening message %q", session.Message)
+	}
+
+	session.Submit("players")
+	if session.Message != "White: bot, Black: human" {
+		t.Fatalf("unexpected players message %q", session.Message)
+	}
+}
+
+func TestSessionGoAdvancesBotGames(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Bot: testBot, White: PlayerBot, Black: PlayerBot})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if len(session.MoveLog) != 0 {
+		t.Fatalf("expected bot games to wait for go, got %d moves", len(session.MoveLog))
+	}
+	session.Submit("go")
+	session.Submit("go")
+	if len(session.MoveLog) != 2 {
+		t.Fatalf("expected one move per go, got %d", len(session.MoveLog))
+	}
+
+	// go also lets the bot move for a human side
+	human, _ := NewSessionWithOptions(Options{Bot: testBot})
+	human.Submit("go")
+	if len(human.MoveLog) != 1 || !strings.HasPrefix(human.Message, "White bot played ") {
+		t.Fatalf("expected go to play a move for white, got %q", human.Message)
+	}
+}
+
+func TestSessionAsyncBotSearch(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Bot: testBot, Black: PlayerBot, AsyncBots: true})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	session.Submit("e2e4")
+	if len(session.MoveLog) != 1 || !strings.HasSuffix(session.Message, ". Black bot is thinking...") {
+		t.Fatalf("expected the bot reply to be left to a search, got %d moves and %q", len(session.MoveLog), session.Message)
+	}
+
+	search := session.StartBotSearch()
+	if search == nil || !session.BotThinking() {
+		t.Fatalf("expected a bot search to start")
+	}
+	if session.StartBotSearch() != nil {
+		t.Fatalf("expected no second search while one runs")
+	}
+	if session.Submit("d7d5"); len(session.MoveLog) != 1 {
+		t.Fatalf("expected moves to wait for the bot, got %d moves", len(session.MoveLog))
+	}
+
+	session.FinishBotSearch(search.Run())
+	if len(session.MoveLog) != 2 || !strings.HasPrefix(session.Message, "Black bot played ") || session.BotThinking() {
+		t.Fatalf("expected the bot reply to be played, got %d moves and %q", len(session.MoveLog), session.Message)
+	}
+}
+
+func TestSessionUndoCancelsBotSearch(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Bot: testBot, Black: PlayerBot, AsyncBots: true})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	session.Submit("e2e4")
+	search := session.StartBotSearch()
+
+	session.Submit("undo")
+	if len(session.MoveLog) != 0 || session.BotThinking() {
+		t.Fatalf("expected undo to cancel the search and take back e2e4, got %d moves", len(session.MoveLog))
+	}
+	// the cancelled search returns at once and its move is dropped
+	session.FinishBotSearch(search.Run())
+	if len(session.MoveLog) != 0 || session.Game.FEN() != engine.StartFEN {
+		t.Fatalf("expected a cancelled search to be ignored, got %d moves at %s", len(session.MoveLog), session.Game.FEN())
+	}
+	if session.StartBotSearch() != nil {
+		t.Fatalf("expected no search with white to move")
+	}
+}
+
+func TestSessionAsyncGoSearchesForTheSideToMove(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Bot: testBot, Black: PlayerBot, AsyncBots: true})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if session.StartBotSearch() != nil {
+		t.Fatalf("expected no search with the human to move")
+	}
+	session.Submit("go")
+	search := session.StartBotSearch()
+	if search == nil {
+		t.Fatalf("expected go to start a search for the human side")
+	}
+	session.FinishBotSearch(search.Run())
+	if len(session.MoveLog) != 1 || session.Game.Turn != engine.Black {
+		t.Fatalf("expected go to play one move for white, got %d", len(session.MoveLog))
+	}
+
+	session.StartBotSearch()
+	session.Submit("quit")
+	if session.BotThinking() {
+		t.Fatalf("expected quit to cancel the search")
+	}
+}
+
+func TestSessionAsyncBotsPlayEachOther(t *testing.T) {
+	session, err := NewSessionWithOptions(Options{Bot: testBot, White: PlayerBot, Black: PlayerBot, AsyncBots: true})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if session.Message != "White bot is thinking..." {
+		t.Fatalf("expected white to start thinking, got %q", session.Message)
+	}
+	// each finished search starts the next one, as the TUI does
+	for i := 0; i < 4; i++ {
+		search := session.StartBotSearch()
+		if search == nil {
+			t.Fatalf("expected search %d to start on its own", i+1)
+		}
+		session.FinishBotSearch(search.Run())
+	}
+	if len(session.MoveLog) != 4 || !strings.HasSuffix(session.Message, " bot is thinking...") {
+		t.Fatalf("expected the bots to keep playing, got %d moves and %q", len(session.MoveLog), session.Message)
+	}
+}
+
+func TestParsePlayer(t *testing.T) {
+	for raw, want := range map[string]Player{"": PlayerHuman, "human": PlayerHuman, "Bot": PlayerBot, " EXEC:/opt/Engine --fast ": "exec:/opt/Engine --fast"} {
+		if got, err := ParsePlayer(raw); err != nil || got != want {
+			t.Fatalf("ParsePlayer(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"robot", "exec:", "exec: "} {
+		if _, err := ParsePlayer(raw); err == nil {
+			t.Fatalf("expected an error for player %q", raw)
+		}
+	}
+	if command, ok := Player("exec:./engine --fast").ExecCommand(); !ok || command != "./engine --fast" {
+		t.Fatalf("unexpected exec command %q, %v", command, ok)
+	}
+	if _, ok := PlayerBot.ExecCommand(); ok {
+		t.Fatalf("expected no exec command for the built-in bot")
+	}
+	if _, err := NewSessionWithOptions(Options{White: "robot"}); err == nil {
+		t.Fatalf("expected NewSessionWithOptions to reject an unknown player")
+	}
+}
+
+func TestSessionPlaysAnExecEngine(t *testing.T) {
+	command, log := ucitest.StubEngine(t, "echo 'bestmove e7e5'")
+	player := Player(playerExec + command)
+	session, err := NewSessionWithOptions(Options{Black: player, Rules: "safe"})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	if session.PlayerFor(engine.Black) != player {
+		t.Fatalf("expected black to be %q, got %q", player, session.PlayerFor(engine.Black))
+	}
+
+	session.Submit("e2e4")
+	if len(session.MoveLog) != 2 || !strings.Contains(session.Message, "Black bot played e7e5") {
+		t.Fatalf("expected the engine's reply, got %d moves and %q", len(session.MoveLog), session.Message)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		"uci\nsetoption name Rules value safe\nisready\n",
+		"played e2e4 ",
+		"position fen ",
+		"go movetime 500\n",
+		"played e7e5 ",
+		"quit\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in the engine log:\n%s", want, text)
+		}
+	}
+}
+
+func TestSessionReportsExecEngineFailures(t *testing.T) {
+	defer func(timeout time.Duration) { engineTimeout = timeout }(engineTimeout)
+	engineTimeout = 100 * time.Millisecond
+
+	for onGo, want := range map[string]string{
+		"exit 3":                   "Bot failed: engine exited: exit status 3",
+		":":                        "Bot failed: engine timed out",
+		"echo 'bestmove e2e4'":     "Bot failed: illegal reply: bestmove e2e4",
+		"echo 'error confused'":    "Bot failed: engine failed: confused",
+		"echo 'bestmove (none)'":   "Bot failed: illegal reply",
+		"echo 'bestmove e7e5 x y'": "Black bot played e7e5",
+	} {
+		command, _ := ucitest.StubEngine(t, onGo)
+		session, err := NewSessionWithOptions(Options{Black: Player(playerExec + command)})
+		if err != nil {
+			t.Fatalf("NewSessionWithOptions returned error: %v", err)
+		}
+		session.Submit("e2e4")
+		if !strings.Contains(session.Message, want) {
+			t.Fatalf("%s: expected %q in the message, got %q", onGo, want, session.Message)
+		}
+		session.Close()
+	}
+}
+
+func TestSessionAsyncBotWaitsForGoAfterAFailure(t *testing.T) {
+	command, _ := ucitest.StubEngine(t, "exit 3")
+	session, err := NewSessionWithOptions(Options{Black: Player(playerExec + command), AsyncBots: true})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	defer session.Close()
+
+	session.Submit("e2e4")
+	search := session.StartBotSearch()
+	if search == nil {
+		t.Fatalf("expected the engine's reply to be searched")
+	}
+	session.FinishBotSearch(search.Run())
+	if !strings.HasPrefix(session.Message, "Bot failed: ") {
+		t.Fatalf("expected the crash to be reported, got %q", session.Message)
+	}
+	if session.StartBotSearch() != nil {
+		t.Fatalf("expected no second search after a failure")
+	}
+	session.Submit("go")
+	if session.StartBotSearch() == nil {
+		t.Fatalf("expected go to ask the engine again")
+	}
+}
+
+func TestNewSessionWithOptionsRejectsBrokenEngines(t *testing.T) {
+	if _, err := NewSessionWithOptions(Options{White: Player(playerExec + filepath.Join(t.TempDir(), "missing"))}); err == nil || !strings.HasPrefix(err.Error(), "White engine: ") {
+		t.Fatalf("expected a missing engine to fail, got %v", err)
+	}
+}
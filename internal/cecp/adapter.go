@@ -0,0 +1,472 @@
+// Package cecp plays the Swapchess engine over the Chess Engine Communication
+// Protocol (CECP) of XBoard and WinBoard, so that established GUIs can run
+// games and tournaments against it.
+//
+// The adapter declares a single variant, swapchess, and takes the GUI's moves
+// with usermove in coordinate notation. A GUI cannot predict swaps, so after
+// every move that swaps, whether the GUI's or the engine's, the adapter sends
+// the resulting board as a standard FEN:
+//
+//	usermove e2e4
+//	setboard rnbqkbnr/pppppppp/8/8/4N3/8/PPPP1PPP/RNBQKBPR b KQkq - 0 1
+//	move e7e5
+//
+// Unknown commands are answered with "Error (unknown command): <command>",
+// and the rules preset and the swap seed are set with the Rules and Seed
+// options.
+package cecp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/engine/bot"
+	"github.com/divijg19/Swapchess/internal/uci"
+)
+
+// Variant is the CECP variant name of Swapchess.
+const Variant = "swapchess"
+
+// ignored lists the commands that need no action or reply.
+var ignored = map[string]bool{
+	"accepted": true, "rejected": true, "random": true, "hard": true, "easy": true,
+	"computer": true, "name": true, "rating": true, "otim": true, "ics": true,
+	"white": true, "black": true, "draw": true, "hint": true, "bk": true,
+	"cores": true, "memory": true, "egtpath": true, "nps": true, ".": true,
+}
+
+// Adapter answers the CECP commands of one session. Create one with
+// NewAdapter.
+type Adapter struct {
+	// Name is sent as the myname feature.
+	Name string
+
+	// mu serializes the output of the command loop and the search.
+	mu  sync.Mutex
+	out io.Writer
+
+	state   *engine.GameState
+	history []engine.Undo
+	rules   engine.Rules
+	seed    int64
+	table   *bot.TranspositionTable
+	// engineColor is the side the engine plays unless force is set.
+	engineColor engine.Color
+	force       bool
+	gameOver    bool
+	post        bool
+
+	// search limits from sd, st, level and time
+	depth      int
+	moveTime   time.Duration
+	clock      time.Duration
+	increment  time.Duration
+	movesPerTC int
+
+	search *search
+}
+
+// search is an engine move in progress.
+type search struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	// discard drops the move instead of playing it.
+	discard atomic.Bool
+}
+
+// NewAdapter returns an adapter that writes to out, set up for a new game in
+// which the engine plays black, as after the new command.
+func NewAdapter(out io.Writer) *Adapter {
+	a := &Adapter{Name: uci.DefaultName, out: out, rules: engine.ClassicRules(), seed: 1, table: bot.NewTranspositionTable(bot.DefaultTableSize)}
+	a.newGame()
+	return a
+}
+
+// Serve reads commands from in until quit or the end of input.
+func (a *Adapter) Serve(in io.Reader) error {
+	defer a.stopSearch(true)
+	lines := bufio.NewScanner(in)
+	for lines.Scan() {
+		if !a.Handle(lines.Text()) {
+			return nil
+		}
+	}
+	return lines.Err()
+}
+
+// Handle runs one command and reports whether the session goes on.
+func (a *Adapter) Handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case "quit":
+		a.stopSearch(true)
+		return false
+	case "?":
+		// move now
+		a.stopSearch(false)
+		return true
+	case "new", "force", "result", "undo", "remove", "setboard", "variant", "option":
+		// these take the move away from a search in progress
+		a.stopSearch(true)
+	default:
+		a.waitSearch()
+	}
+
+	switch {
+	case ignored[command]:
+	case command == "xboard":
+	case command == "protover":
+		a.sendFeatures()
+	case command == "ping":
+		a.send("pong %s", strings.Join(args, " "))
+	case command == "new":
+		a.newGame()
+	case command == "variant":
+		if len(args) != 1 || args[0] != Variant {
+			a.send("Error (unsupported variant): %s", strings.Join(args, " "))
+		}
+	case command == "force":
+		a.force = true
+	case command == "go":
+		a.force = false
+		a.engineColor = a.state.Turn
+		a.think()
+	case command == "playother":
+		a.force = false
+		a.engineColor = 1 - a.state.Turn
+	case command == "usermove":
+		a.userMove(strings.Join(args, " "))
+	case command == "setboard":
+		a.setBoard(strings.Join(args, " "))
+	case command == "undo":
+		a.undo(1)
+	case command == "remove":
+		a.undo(2)
+	case command == "result":
+		a.gameOver = true
+	case command == "post":
+		a.post = true
+	case command == "nopost":
+		a.post = false
+	case command == "sd", command == "st", command == "level", command == "time":
+		a.setLimit(command, args)
+	case command == "option":
+		a.setOption(strings.Join(args, " "))
+	default:
+		// GUIs without usermove send bare moves
+		if move, err := uci.ParseMove(command); err == nil && len(args) == 0 {
+			a.playUserMove(command, move)
+			return true
+		}
+		a.send("Error (unknown command): %s", command)
+	}
+	return true
+}
+
+func (a *Adapter) send(format string, args ...any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fmt.Fprintf(a.out, format+"\n", args...)
+}
+
+func (a *Adapter) sendFeatures() {
+	a.send("feature done=0")
+	a.send(`feature myname="%s" variants="%s" usermove=1 setboard=1 ping=1 playother=1 colors=0 time=1 draw=0 sigint=0 sigterm=0 reuse=1 analyze=0 san=0`, a.Name, Variant)
+	a.send(`feature option="Rules -combo %s"`, rulesCombo(a.rules.Name))
+	a.send(`feature option="Seed -spin %d 0 2147483647"`, a.seed)
+	a.send("feature done=1")
+}
+
+// rulesCombo lists the presets as a CECP combo, marking the current one.
+func rulesCombo(current string) string {
+	names := engine.RulesPresetNames()
+	for i, name := range names {
+		if name == current {
+			names[i] = "*" + name
+		}
+	}
+	return strings.Join(names, " /// ")
+}
+
+func (a *Adapter) newGame() {
+	a.state = engine.NewGame()
+	a.applyRules()
+	a.state.RandSeed = a.seed
+	a.history = nil
+	a.engineColor = engine.Black
+	a.force = false
+	a.gameOver = false
+	a.depth = 0
+	a.table.Clear()
+}
+
+func (a *Adapter) applyRules() {
+	rules := a.rules
+	a.state.Rules = &rules
+}
+
+func (a *Adapter) setOption(arg string) {
+	name, value, ok := strings.Cut(arg, "=")
+	switch {
+	case ok && name == "Rules":
+		rules, err := engine.RulesPreset(value)
+		if err != nil {
+			a.send("Error (unknown rules): %s", value)
+			return
+		}
+		a.rules = rules
+		a.applyRules()
+		// the table does not tell positions under different rules apart
+		a.table.Clear()
+	case ok && name == "Seed":
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			a.send("Error (invalid seed): %s", value)
+			return
+		}
+		a.seed = seed
+		a.state.RandSeed = seed
+	default:
+		a.send("Error (unknown option): %s", arg)
+	}
+}
+
+// setBoard sets the position. A plain FEN keeps the current swap seed.
+func (a *Adapter) setBoard(fen string) {
+	state, err := engine.ParseFEN(fen)
+	if err != nil {
+		a.send("tellusererror Illegal position: %v", err)
+		return
+	}
+	if len(strings.Fields(fen)) < 8 {
+		state.RandSeed = a.state.RandSeed
+	}
+	a.state = state
+	a.applyRules()
+	a.history = nil
+	a.gameOver = engine.GameOutcome(state) != engine.OutcomeNone
+}
+
+func (a *Adapter) setLimit(command string, args []string) {
+	number := func(i int) (float64, bool) {
+		if i >= len(args) {
+			return 0, false
+		}
+		// level takes its base time as minutes or minutes:seconds
+		minutes, seconds, hasSeconds := strings.Cut(args[i], ":")
+		n, err := strconv.ParseFloat(minutes, 64)
+		if err != nil {
+			return 0, false
+		}
+		if hasSeconds {
+			s, err := strconv.ParseFloat(seconds, 64)
+			if err != nil {
+				return 0, false
+			}
+			n += s / 60
+		}
+		return n, true
+	}
+
+	ok := true
+	switch command {
+	case "sd":
+		var n float64
+		n, ok = number(0)
+		a.depth = int(n)
+	case "st":
+		var n float64
+		n, ok = number(0)
+		a.moveTime = time.Duration(n * float64(time.Second))
+	case "level":
+		mps, ok1 := number(0)
+		base, ok2 := number(1)
+		inc, ok3 := number(2)
+		ok = ok1 && ok2 && ok3
+		a.movesPerTC = int(mps)
+		a.clock = time.Duration(base * float64(time.Minute))
+		a.increment = time.Duration(inc * float64(time.Second))
+	case "time":
+		var n float64
+		n, ok = number(0)
+		// centiseconds
+		a.clock = time.Duration(n) * 10 * time.Millisecond
+	}
+	if !ok {
+		a.send("Error (invalid %s): %s", command, strings.Join(args, " "))
+	}
+}
+
+// limits returns the limits of the engine's next search.
+func (a *Adapter) limits() bot.SearchLimits {
+	limits := bot.SearchLimits{Depth: a.depth, MoveTime: a.moveTime}
+	if limits.MoveTime == 0 && a.clock > 0 {
+		moves := 30
+		if a.movesPerTC > 0 {
+			moves = a.movesPerTC - (a.state.FullmoveNumber-1)%a.movesPerTC
+		}
+		limits.MoveTime = a.clock/time.Duration(moves) + a.increment/2
+	}
+	if limits.Depth == 0 && limits.MoveTime == 0 {
+		limits.Depth = bot.DefaultDepth
+	}
+	return limits
+}
+
+func (a *Adapter) userMove(raw string) {
+	move, err := uci.ParseMove(raw)
+	if err != nil {
+		a.send("Error (bad move): %s", raw)
+		return
+	}
+	a.playUserMove(raw, move)
+}
+
+func (a *Adapter) playUserMove(raw string, move engine.Move) {
+	if a.gameOver {
+		a.send("Illegal move (game over): %s", raw)
+		return
+	}
+	if _, err := a.play(move); err != nil {
+		a.send("Illegal move: %s", raw)
+		return
+	}
+	a.report()
+	if !a.gameOver && !a.force && a.state.Turn == a.engineColor {
+		a.think()
+	}
+}
+
+// play makes a move, reports the board if it swapped and the result if it
+// ended the game.
+func (a *Adapter) play(move engine.Move) (engine.MoveResult, error) {
+	res, u, err := engine.MakeMove(a.state, move)
+	if err != nil {
+		return res, err
+	}
+	a.history = append(a.history, u)
+	if res.Swapped {
+		a.sendBoard()
+	}
+	return res, nil
+}
+
+// report sends the result once the game is over.
+func (a *Adapter) report() {
+	outcome := engine.GameOutcome(a.state)
+	if outcome == engine.OutcomeNone {
+		return
+	}
+	a.gameOver = true
+	a.send("%s {%s}", uci.ResultString(a.state, outcome), resultComment(a.state, outcome))
+}
+
+// sendBoard sends the position as a standard FEN, without the SwapFEN fields
+// a GUI would not understand.
+func (a *Adapter) sendBoard() {
+	fields := strings.Fields(a.state.FEN())
+	a.send("setboard %s", strings.Join(fields[:6], " "))
+}
+
+func resultComment(state *engine.GameState, outcome engine.Outcome) string {
+	switch outcome {
+	case engine.OutcomeCheckmate:
+		return fmt.Sprintf("%s mates", 1-state.Turn)
+	case engine.OutcomeKingCaptured:
+		return fmt.Sprintf("%s king captured", state.Turn)
+	case engine.OutcomeStalemate:
+		return "Stalemate"
+	case engine.OutcomeFiftyMoveRule:
+		return "50 move rule"
+	case engine.OutcomeThreefoldRepetition:
+		return "Draw by repetition"
+	case engine.OutcomeInsufficientMaterial:
+		return "Insufficient material"
+	}
+	return outcome.String()
+}
+
+func (a *Adapter) undo(plies int) {
+	if len(a.history) < plies {
+		a.send("Error (cannot undo): %d moves played", len(a.history))
+		return
+	}
+	for range plies {
+		engine.UnmakeMove(a.state, &a.history[len(a.history)-1])
+		a.history = a.history[:len(a.history)-1]
+	}
+	a.gameOver = false
+	// the GUI's board may not have the swaps that were taken back
+	a.sendBoard()
+}
+
+// think searches for the engine's move in the background, so that ? and the
+// commands that take the move away are answered meanwhile.
+func (a *Adapter) think() {
+	if a.gameOver {
+		return
+	}
+	if a.report(); a.gameOver {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	limits := a.limits()
+	limits.Context = ctx
+	srch := &search{cancel: cancel, done: make(chan struct{})}
+	a.search = srch
+	state := a.state.Clone()
+	go func() {
+		defer close(srch.done)
+		defer cancel()
+		started := time.Now()
+		res, err := bot.Expectimax{Table: a.table}.SearchWithLimits(state, limits)
+		if srch.discard.Load() {
+			return
+		}
+		if err != nil {
+			a.send("Error (no move): %v", err)
+			return
+		}
+		if a.post {
+			a.send("%d %d %d %d %s", res.Depth, int(res.Score*100), time.Since(started).Milliseconds()/10, res.Nodes, uci.FormatMove(res.Move))
+		}
+		a.send("move %s", uci.FormatMove(res.Move))
+		if _, err := a.play(res.Move); err != nil {
+			a.send("Error (engine move): %v", err)
+			return
+		}
+		a.report()
+	}()
+}
+
+// stopSearch stops the search in progress, if any, and waits for it to play
+// its move or, with discard, to drop it.
+func (a *Adapter) stopSearch(discard bool) {
+	if a.search == nil {
+		return
+	}
+	a.search.discard.Store(discard)
+	a.search.cancel()
+	a.waitSearch()
+}
+
+// waitSearch waits for the search in progress, if any, to play its move.
+func (a *Adapter) waitSearch() {
+	if a.search != nil {
+		<-a.search.done
+		a.search = nil
+	}
+}
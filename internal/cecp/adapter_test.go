@@ -0,0 +1,186 @@
+package cecp
+
+import (
+	"strings"
+	"testing"
+)
+
+// runTranscript plays a CECP transcript: lines starting with "> " are sent
+// to the adapter and lines starting with "< " are its expected replies, in
+// order. Feature lines are left out of the comparison unless the transcript
+// lists them.
+func runTranscript(t *testing.T, transcript string) {
+	t.Helper()
+	var commands, want []string
+	for _, line := range strings.Split(strings.TrimSpace(transcript), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "> "):
+			commands = append(commands, line[2:])
+		case strings.HasPrefix(line, "< "):
+			want = append(want, line[2:])
+		}
+	}
+
+	var out strings.Builder
+	if err := NewAdapter(&out).Serve(strings.NewReader(strings.Join(commands, "\n") + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if !strings.Contains(transcript, "< feature") {
+		got = dropFeatures(got)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected replies:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func dropFeatures(lines []string) []string {
+	var kept []string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "feature ") && line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+func TestAdapterDeclaresTheVariant(t *testing.T) {
+	runTranscript(t, `
+		> xboard
+		> protover 2
+		< feature done=0
+		< feature myname="Swapchess" variants="swapchess" usermove=1 setboard=1 ping=1 playother=1 colors=0 time=1 draw=0 sigint=0 sigterm=0 reuse=1 analyze=0 san=0
+		< feature option="Rules -combo *classic /// double /// gentle /// kin /// local /// relentless /// safe"
+		< feature option="Seed -spin 1 0 2147483647"
+		< feature done=1
+		> accepted usermove
+		> new
+		> variant swapchess
+		> variant crazyhouse
+		< Error (unsupported variant): crazyhouse
+	`)
+}
+
+func TestAdapterReportsSwapsWithSetboard(t *testing.T) {
+	// with seed 1, Nf3 swaps the knight with the bishop on f1, and Nc6 with the
+	// pawn on d7
+	runTranscript(t, `
+		> xboard
+		> new
+		> force
+		> usermove g1f3
+		< setboard rnbqkbnr/pppppppp/8/8/8/5B2/PPPPPPPP/RNBQKN1R b KQkq - 1 1
+		> usermove b8c6
+		< setboard r1bqkbnr/pppnpppp/2p5/8/8/5B2/PPPPPPPP/RNBQKN1R w KQkq - 0 2
+		> ping 1
+		< pong 1
+		> remove
+		< setboard rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+	`)
+}
+
+func TestAdapterRejectsBadInput(t *testing.T) {
+	runTranscript(t, `
+		> new
+		> force
+		> usermove e2e5
+		< Illegal move: e2e5
+		> usermove e9
+		< Error (bad move): e9
+		> foo
+		< Error (unknown command): foo
+		> undo
+		< Error (cannot undo): 0 moves played
+		> option Speed=1
+		< Error (unknown option): Speed=1
+		> option Rules=wild
+		< Error (unknown rules): wild
+		> sd x
+		< Error (invalid sd): x
+		> setboard 8/8 w
+		< tellusererror Illegal position: invalid fen: expected 4 to 8 fields, got 2
+	`)
+}
+
+func TestAdapterPlaysAndReportsTheResult(t *testing.T) {
+	runTranscript(t, `
+		> new
+		> setboard 6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1
+		> sd 2
+		> go
+		< move a1a8
+		< 1-0 {White mates}
+		> usermove g8h8
+		< Illegal move (game over): g8h8
+		> new
+		> force
+		> setboard 6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1
+		> usermove a1a8
+		< 1-0 {White mates}
+	`)
+}
+
+func TestAdapterRepliesToUserMoves(t *testing.T) {
+	var out strings.Builder
+	a := NewAdapter(&out)
+	for _, command := range []string{"new", "option Rules=safe", "option Seed=7", "sd 1", "post", "e2e4", "ping 1"} {
+		a.Handle(command)
+	}
+	a.Handle("quit")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if lines[len(lines)-1] != "pong 1" {
+		t.Fatalf("expected the engine to reply before pong, got %q", lines)
+	}
+	var moved, thought bool
+	for _, line := range lines {
+		moved = moved || strings.HasPrefix(line, "move ")
+		thought = thought || strings.HasPrefix(line, "1 ")
+	}
+	if !moved || !thought {
+		t.Fatalf("expected thinking output and an engine move, got %q", lines)
+	}
+	if a.state.RandSeed != 7 || a.state.Rules.Name != "safe" || len(a.history) != 2 {
+		t.Fatalf("expected two moves under the options, got seed %d, rules %s, %d moves", a.state.RandSeed, a.state.Rules.Name, len(a.history))
+	}
+}
+
+func TestAdapterMovesNowOrDropsTheSearch(t *testing.T) {
+	var out strings.Builder
+	a := NewAdapter(&out)
+	a.Handle("st 30")
+	a.Handle("go")
+	a.Handle("?")
+	if !strings.HasPrefix(out.String(), "move ") || len(a.history) != 1 {
+		t.Fatalf("expected ? to play the move at once, got %q", out.String())
+	}
+
+	out.Reset()
+	a.Handle("go")
+	a.Handle("force")
+	if out.Len() != 0 || len(a.history) != 1 {
+		t.Fatalf("expected force to drop the search, got %q", out.String())
+	}
+}
+
+func TestAdapterLimits(t *testing.T) {
+	a := NewAdapter(&strings.Builder{})
+	if limits := a.limits(); limits.Depth != 2 || limits.MoveTime != 0 {
+		t.Fatalf("expected the default depth without limits, got %+v", limits)
+	}
+	a.Handle("level 40 5 0")
+	a.Handle("time 12000")
+	if limits := a.limits(); limits.MoveTime.Milliseconds() != 3000 {
+		t.Fatalf("expected 120s over 40 moves, got %+v", limits)
+	}
+	a.Handle("level 0 2:30 2")
+	a.Handle("time 6000")
+	if limits := a.limits(); limits.MoveTime.Milliseconds() != 3000 {
+		t.Fatalf("expected 60s over 30 moves plus half the increment, got %+v", limits)
+	}
+	a.Handle("st 0.5")
+	if limits := a.limits(); limits.MoveTime.Milliseconds() != 500 {
+		t.Fatalf("expected st to fix the move time, got %+v", limits)
+	}
+}
@@ -0,0 +1,266 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+var (
+	ErrEngineExited  = errors.New("engine exited")
+	ErrEngineTimeout = errors.New("engine timed out")
+	ErrEngineFailed  = errors.New("engine failed")
+	ErrIllegalReply  = errors.New("illegal reply")
+)
+
+// DefaultEngineTimeout is how long an engine may take to start, and to answer
+// beyond its move time, when EngineOptions leaves Timeout unset.
+const DefaultEngineTimeout = 5 * time.Second
+
+// EngineOptions configures StartEngine.
+type EngineOptions struct {
+	// Command is the program to run followed by its arguments, separated by
+	// spaces.
+	Command string
+	// Rules are sent to the engine with setoption unless they are classic.
+	Rules engine.Rules
+	// MoveTime is sent with each go command; 0 lets the engine decide.
+	MoveTime time.Duration
+	// Timeout is how long the engine may take to start, and to answer beyond
+	// MoveTime; 0 means DefaultEngineTimeout.
+	Timeout time.Duration
+}
+
+// Engine is an external process that speaks the protocol as a server does,
+// playing as a bot.ContextBot. It is told of every move of the game with
+// Played and asked for its own moves with go:
+//
+//	played <move and swaps, as in the reply to move>
+//	position fen <SwapFEN>
+//	go movetime <ms>
+//
+// A reply that is not a legal move, no reply in time, or the process ending
+// fails the search with ErrIllegalReply, ErrEngineTimeout or ErrEngineExited.
+type Engine struct {
+	name     string
+	moveTime time.Duration
+	timeout  time.Duration
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// lines carries the engine's replies and is closed once it has exited,
+	// after exitErr is set.
+	lines   chan string
+	exitErr error
+
+	// writeMu serializes commands.
+	writeMu sync.Mutex
+	// searchMu serializes searches and guards stale.
+	searchMu sync.Mutex
+	// stale counts the bestmove replies still owed to searches that were
+	// abandoned, which the next search skips.
+	stale int
+}
+
+// StartEngine runs the engine command and completes the uci handshake.
+func StartEngine(opts EngineOptions) (*Engine, error) {
+	args := strings.Fields(opts.Command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: empty command", ErrEngineFailed)
+	}
+	e := &Engine{
+		name:     filepath.Base(args[0]),
+		moveTime: opts.MoveTime,
+		timeout:  opts.Timeout,
+		cmd:      exec.Command(args[0], args[1:]...),
+		lines:    make(chan string, 64),
+	}
+	if e.timeout <= 0 {
+		e.timeout = DefaultEngineTimeout
+	}
+	stdout, err := e.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if e.stdin, err = e.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if err := e.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEngineFailed, err)
+	}
+	go e.read(stdout)
+
+	if err := e.handshake(opts.Rules); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// read passes the engine's replies on until it exits.
+func (e *Engine) read(stdout io.Reader) {
+	lines := bufio.NewScanner(stdout)
+	for lines.Scan() {
+		e.lines <- lines.Text()
+	}
+	e.exitErr = e.cmd.Wait()
+	close(e.lines)
+}
+
+func (e *Engine) handshake(rules engine.Rules) error {
+	e.send("uci")
+	if err := e.await("uciok", func(fields []string) {
+		if len(fields) > 2 && fields[0] == "id" && fields[1] == "name" {
+			e.name = strings.Join(fields[2:], " ")
+		}
+	}); err != nil {
+		return err
+	}
+	if rules.Name != "" && rules.Name != engine.ClassicRules().Name {
+		e.send("setoption name Rules value %s", rules.Name)
+	}
+	e.send("isready")
+	return e.await("readyok", nil)
+}
+
+// await reads replies until one is want, passing the others to seen.
+func (e *Engine) await(want string, seen func(fields []string)) error {
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return e.exited()
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == want:
+				return nil
+			case fields[0] == "error":
+				return fmt.Errorf("%w: %s", ErrEngineFailed, strings.TrimPrefix(line, "error "))
+			case seen != nil:
+				seen(fields)
+			}
+		case <-timer.C:
+			return fmt.Errorf("%w: no %s after %s", ErrEngineTimeout, want, e.timeout)
+		}
+	}
+}
+
+func (e *Engine) exited() error {
+	if e.exitErr != nil {
+		return fmt.Errorf("%w: %v", ErrEngineExited, e.exitErr)
+	}
+	return ErrEngineExited
+}
+
+// send writes a command, ignoring errors: a dead engine shows up as the end
+// of its replies.
+func (e *Engine) send(format string, args ...any) {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	fmt.Fprintf(e.stdin, format+"\n", args...)
+}
+
+// Name is the name the engine reported, or its program's name.
+func (e *Engine) Name() string { return e.name }
+
+// Played tells the engine about a move played in the game and the position
+// it led to.
+func (e *Engine) Played(res engine.MoveResult, state *engine.GameState) {
+	e.send("played %s", FormatResult(res))
+	e.send("position fen %s", state.FEN())
+}
+
+func (e *Engine) ChooseMove(state *engine.GameState) (engine.Move, error) {
+	return e.ChooseMoveContext(context.Background(), state)
+}
+
+// ChooseMoveContext asks the engine for a move, telling it to stop when ctx
+// is done.
+func (e *Engine) ChooseMoveContext(ctx context.Context, state *engine.GameState) (engine.Move, error) {
+	e.searchMu.Lock()
+	defer e.searchMu.Unlock()
+
+	e.send("position fen %s", state.FEN())
+	if e.moveTime > 0 {
+		e.send("go movetime %d", e.moveTime.Milliseconds())
+	} else {
+		e.send("go")
+	}
+	timer := time.NewTimer(e.moveTime + e.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return engine.Move{}, e.exited()
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "bestmove" && e.stale > 0:
+				e.stale--
+			case fields[0] == "bestmove":
+				return bestMove(state, fields[1:])
+			case fields[0] == "error":
+				return engine.Move{}, fmt.Errorf("%w: %s", ErrEngineFailed, strings.TrimPrefix(line, "error "))
+			}
+		case <-timer.C:
+			e.abandon()
+			return engine.Move{}, fmt.Errorf("%w: no move after %s", ErrEngineTimeout, e.moveTime+e.timeout)
+		case <-ctx.Done():
+			e.abandon()
+			return engine.Move{}, ctx.Err()
+		}
+	}
+}
+
+// abandon stops the search in progress, whose bestmove is then skipped.
+func (e *Engine) abandon() {
+	e.send("stop")
+	e.stale++
+}
+
+// bestMove checks the move of a bestmove reply.
+func bestMove(state *engine.GameState, args []string) (engine.Move, error) {
+	if len(args) == 0 {
+		return engine.Move{}, fmt.Errorf("%w: bestmove without a move", ErrIllegalReply)
+	}
+	move, err := ParseMove(args[0])
+	if err != nil || !slices.Contains(engine.LegalMoves(state), move) {
+		return engine.Move{}, fmt.Errorf("%w: bestmove %s", ErrIllegalReply, args[0])
+	}
+	return move, nil
+}
+
+// Close asks the engine to quit and kills it if it has not within the
+// timeout.
+func (e *Engine) Close() error {
+	e.send("quit")
+	e.stdin.Close()
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case _, ok := <-e.lines:
+			if !ok {
+				return nil
+			}
+		case <-timer.C:
+			return e.cmd.Process.Kill()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/internal/uci/ucitest"
+)
+
+// TestHelperServer is not a test: it serves the protocol when the engine
+// tests run the test binary as an engine process.
+func TestHelperServer(t *testing.T) {
+	if os.Getenv("SWAPCHESS_HELPER_SERVER") != "1" {
+		t.Skip("only runs as an engine process")
+	}
+	NewServer(os.Stdout).Serve(os.Stdin)
+	os.Exit(0)
+}
+
+func startHelperEngine(t *testing.T, rules engine.Rules) *Engine {
+	t.Helper()
+	t.Setenv("SWAPCHESS_HELPER_SERVER", "1")
+	e, err := StartEngine(EngineOptions{Command: os.Args[0] + " -test.run=^TestHelperServer$", Rules: rules, MoveTime: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestEnginePlaysAServerProcess(t *testing.T) {
+	rules, _ := engine.RulesPreset("safe")
+	e := startHelperEngine(t, rules)
+	if e.Name() != DefaultName {
+		t.Fatalf("expected the engine to report its name, got %q", e.Name())
+	}
+
+	state := engine.NewGame()
+	state.Rules = &rules
+	move, _ := ParseMove("e2e4")
+	res, err := engine.ApplyMoveResult(state, move)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Played(res, state)
+
+	reply, err := e.ChooseMove(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(engine.LegalMoves(state), reply) {
+		t.Fatalf("expected a legal reply, got %s", FormatMove(reply))
+	}
+}
+
+func TestEngineSkipsTheReplyOfAnAbandonedSearch(t *testing.T) {
+	e := startHelperEngine(t, engine.ClassicRules())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := e.ChooseMoveContext(ctx, engine.NewGame()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled search, got %v", err)
+	}
+
+	// a white move would be illegal here, so the stale reply must be skipped
+	state, err := engine.ParseFEN("4k3/8/8/8/8/8/8/R3K3 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := e.ChooseMove(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(engine.LegalMoves(state), reply) {
+		t.Fatalf("expected a legal reply, got %s", FormatMove(reply))
+	}
+}
+
+func TestEngineFailures(t *testing.T) {
+	for name, tc := range map[string]struct {
+		onGo string
+		want error
+	}{
+		"illegal":   {"echo 'bestmove e2e5'", ErrIllegalReply},
+		"garbled":   {"echo 'bestmove x'", ErrIllegalReply},
+		"crash":     {"exit 3", ErrEngineExited},
+		"silent":    {":", ErrEngineTimeout},
+		"error":     {"echo 'error no position'", ErrEngineFailed},
+		"no move":   {"echo bestmove", ErrIllegalReply},
+		"stalemate": {"echo 'bestmove 0000'", ErrIllegalReply},
+	} {
+		t.Run(name, func(t *testing.T) {
+			command, _ := ucitest.StubEngine(t, tc.onGo)
+			e, err := StartEngine(EngineOptions{Command: command, MoveTime: 10 * time.Millisecond, Timeout: 200 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Close()
+			if e.Name() != "stub" {
+				t.Fatalf("expected the stub's name, got %q", e.Name())
+			}
+			if _, err := e.ChooseMove(engine.NewGame()); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestStartEngineFailures(t *testing.T) {
+	if _, err := StartEngine(EngineOptions{}); !errors.Is(err, ErrEngineFailed) {
+		t.Fatalf("expected an empty command to fail, got %v", err)
+	}
+	if _, err := StartEngine(EngineOptions{Command: filepath.Join(t.TempDir(), "missing")}); !errors.Is(err, ErrEngineFailed) {
+		t.Fatalf("expected a missing program to fail, got %v", err)
+	}
+	// an engine that never completes the handshake
+	silent, _ := ucitest.StubEngine(t, ":")
+	os.WriteFile(silent, []byte("#!/bin/sh\nexec sleep 5\n"), 0o755)
+	if _, err := StartEngine(EngineOptions{Command: silent, Timeout: 100 * time.Millisecond}); !errors.Is(err, ErrEngineTimeout) {
+		t.Fatalf("expected a silent engine to time out, got %v", err)
+	}
+}
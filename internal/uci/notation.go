@@ -0,0 +1,104 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+var promotionLetters = map[engine.PieceKind]byte{
+	engine.Queen:  'q',
+	engine.Rook:   'r',
+	engine.Bishop: 'b',
+	engine.Knight: 'n',
+}
+
+// ParseMove parses a move in coordinate notation, such as e2e4 or e7e8q.
+func ParseMove(s string) (engine.Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return engine.Move{}, fmt.Errorf("%w %q; expected e2e4 or e7e8q", ErrInvalidMove, s)
+	}
+	from, err := engine.ParseSquare(s[0:2])
+	if err != nil {
+		return engine.Move{}, fmt.Errorf("%w %q: %v", ErrInvalidMove, s, err)
+	}
+	to, err := engine.ParseSquare(s[2:4])
+	if err != nil {
+		return engine.Move{}, fmt.Errorf("%w %q: %v", ErrInvalidMove, s, err)
+	}
+	move := engine.Move{From: from, To: to}
+	if len(s) == 5 {
+		for kind, letter := range promotionLetters {
+			if s[4] == letter {
+				move.Promotion, move.PromotionSet = kind, true
+			}
+		}
+		if !move.PromotionSet {
+			return engine.Move{}, fmt.Errorf("%w %q: unknown promotion piece %q", ErrInvalidMove, s, s[4])
+		}
+	}
+	return move, nil
+}
+
+// FormatMove returns the coordinate notation of a move.
+func FormatMove(m engine.Move) string {
+	s := engine.SquareString(m.From) + engine.SquareString(m.To)
+	if m.HasExplicitPromotion() {
+		s += string(promotionLetters[m.Promotion])
+	}
+	return s
+}
+
+// Suppression returns the protocol token of a suppressed swap, as in
+// "noswap check".
+func Suppression(s engine.SwapSuppression) string {
+	switch s {
+	case engine.SuppressedByCheck:
+		return "check"
+	case engine.SuppressedAfterCheck:
+		return "aftercheck"
+	case engine.SuppressedNoCandidates:
+		return "nocandidates"
+	case engine.SuppressedKingCaptured:
+		return "kingcaptured"
+	case engine.SuppressedByRules:
+		return "rules"
+	case engine.SuppressedByChance:
+		return "chance"
+	default:
+		return "none"
+	}
+}
+
+// FormatResult describes a played move and its swaps as in the reply to the
+// move command, without the leading "played".
+func FormatResult(res engine.MoveResult) string {
+	var b strings.Builder
+	b.WriteString(FormatMove(res.Move))
+	if !res.Swapped {
+		b.WriteString(" noswap " + Suppression(res.Suppression))
+		return b.String()
+	}
+	b.WriteString(" swap")
+	steps := append([]engine.SwapStep{{A: res.SwapA, B: res.SwapB, Promoted: res.SwapPromoted}}, res.ExtraSwaps...)
+	for _, step := range steps {
+		b.WriteString(" " + engine.SquareString(step.A) + engine.SquareString(step.B))
+		if step.Promoted {
+			b.WriteByte('q')
+		}
+	}
+	return b.String()
+}
+
+// ResultString returns the PGN result of a finished game, e.g. "1-0".
+func ResultString(state *engine.GameState, outcome engine.Outcome) string {
+	switch {
+	case outcome.IsDraw():
+		return "1/2-1/2"
+	case state.Turn == engine.White:
+		// the side to move has lost
+		return "0-1"
+	}
+	return "1-0"
+}
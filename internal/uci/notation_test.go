@@ -0,0 +1,51 @@
+package uci
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+func TestParseMoveRoundTrips(t *testing.T) {
+	for _, raw := range []string{"e2e4", "a7a8q", "h2h1n", "b7c8r", "g2f1b"} {
+		move, err := ParseMove(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", raw, err)
+		}
+		if got := FormatMove(move); got != raw {
+			t.Fatalf("expected %s to round trip, got %s", raw, got)
+		}
+	}
+	for _, raw := range []string{"", "e2", "e2e4qq", "i2e4", "e2e9", "e7e8k", "E2E4"} {
+		if _, err := ParseMove(raw); !errors.Is(err, ErrInvalidMove) {
+			t.Fatalf("%q: expected ErrInvalidMove, got %v", raw, err)
+		}
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	sq := func(s string) engine.Position {
+		pos, err := engine.ParseSquare(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pos
+	}
+	move, _ := ParseMove("e2e4")
+	for _, tc := range []struct {
+		res  engine.MoveResult
+		want string
+	}{
+		{engine.MoveResult{Move: move, Suppression: engine.SuppressedByCheck}, "e2e4 noswap check"},
+		{engine.MoveResult{Move: move, Suppression: engine.SuppressedByChance}, "e2e4 noswap chance"},
+		{engine.MoveResult{Move: move, Swapped: true, SwapA: sq("e4"), SwapB: sq("b1")}, "e2e4 swap e4b1"},
+		{engine.MoveResult{Move: move, Swapped: true, SwapA: sq("e4"), SwapB: sq("a8"), SwapPromoted: true}, "e2e4 swap e4a8q"},
+		{engine.MoveResult{Move: move, Swapped: true, SwapA: sq("e4"), SwapB: sq("b1"),
+			ExtraSwaps: []engine.SwapStep{{A: sq("b1"), B: sq("c3")}}}, "e2e4 swap e4b1 b1c3"},
+	} {
+		if got := FormatResult(tc.res); got != tc.want {
+			t.Fatalf("expected %q, got %q", tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// Package uci serves the Swapchess engine over a UCI-style line protocol, so
+// that GUIs, scripts and other tools can drive it as a separate process.
+//
+// Commands arrive one per line and every reply is a line of its own. The
+// commands are those of UCI where they apply, extended for swaps:
+//
+//	uci                  identify the engine; replies id, option and uciok lines
+//	isready              reply readyok, even during a search
+//	ucinewgame           reset to the start position and forget past searches
+//	setoption name Rules value <preset>
+//	                     play by an engine.RulesPreset from the next position on
+//	position startpos|fen <SwapFEN> [moves <move>...]
+//	                     set the position, playing the moves with their swaps
+//	seed <n>             set the swap seed of the current position and of
+//	                     later ones whose FEN, like startpos, has no seed
+//	fen                  reply "fen <SwapFEN>" for the current position
+//	legal                reply "legal <move>..." with every legal move
+//	move <move>          play a move; replies played, fen and, when the game
+//	                     ends, result lines
+//	played ...           a move played elsewhere, as the Engine client sends
+//	                     it; ignored, since a position command follows
+//	go [depth <n>] [nodes <n>] [movetime <ms>] [wtime <ms>] [btime <ms>]
+//	   [winc <ms>] [binc <ms>] [movestogo <n>] [infinite]
+//	                     search the current position; replies an info line
+//	                     and "bestmove <move>", or "bestmove 0000" when the
+//	                     game is over; with infinite, bestmove waits for stop
+//	stop                 end the search and reply its best move
+//	quit                 end the session
+//
+// Moves use coordinate notation such as e2e4 or e7e8q. Since swaps depend on
+// the seed, a SwapFEN and a seed fix every swap that follows.
+//
+// The reply to move describes the swap the move made:
+//
+//	played e2e4 swap e4b1        the moved piece went from e4 to b1
+//	played e2e4 swap e4b1 b1c3   Rules.Swaps above 1 chains more swaps
+//	played e2e4 swap e4h8q       a trailing q marks a pawn the swap promoted
+//	played e2e4 noswap check     no swap; see Suppression for the reasons
+//
+// followed by the new position and, once the game is over, its result:
+//
+//	fen <SwapFEN>
+//	result 1-0 checkmate
+//
+// A command that fails replies "error <reason>" and changes nothing. Unknown
+// commands are reported the same way.
+//
+// Engine runs such a server, or any program speaking the protocol, as a
+// child process and plays it as a bot.
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/engine/bot"
+)
+
+var (
+	ErrInvalidMove    = errors.New("invalid move")
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// DefaultName is the engine name a Server reports without one.
+const DefaultName = "Swapchess"
+
+// Server answers the commands of one session. Create one with NewServer.
+type Server struct {
+	// Name is reported by the uci command.
+	Name string
+
+	// mu serializes the replies of the command loop and the search.
+	mu  sync.Mutex
+	out io.Writer
+
+	state *engine.GameState
+	rules engine.Rules
+	// seed is the swap seed of positions set without one.
+	seed   int64
+	table  *bot.TranspositionTable
+	search *search
+}
+
+// search is a go command in progress.
+type search struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewServer returns a server that writes its replies to out, starting at the
+// standard position under the classic rules.
+func NewServer(out io.Writer) *Server {
+	s := &Server{Name: DefaultName, out: out, rules: engine.ClassicRules(), seed: 1, table: bot.NewTranspositionTable(bot.DefaultTableSize)}
+	s.reset()
+	return s
+}
+
+// Serve reads commands from in until quit or the end of input, and ends any
+// search in progress before it returns.
+func (s *Server) Serve(in io.Reader) error {
+	defer s.stop()
+	lines := bufio.NewScanner(in)
+	for lines.Scan() {
+		if !s.Handle(lines.Text()) {
+			return nil
+		}
+	}
+	return lines.Err()
+}
+
+// Handle runs one command and reports whether the session goes on.
+func (s *Server) Handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case "quit":
+		return false
+	case "stop":
+		s.stop()
+		return true
+	case "isready":
+		s.send("readyok")
+		return true
+	}
+
+	// the rest act on the position, so they wait for the search to end
+	s.wait()
+	var err error
+	switch command {
+	case "uci":
+		s.send("id name %s", s.Name)
+		s.send("option name Rules type combo default classic%s", " var "+strings.Join(engine.RulesPresetNames(), " var "))
+		s.send("uciok")
+	case "ucinewgame":
+		s.reset()
+		s.table.Clear()
+	case "setoption":
+		err = s.setOption(args)
+	case "position":
+		err = s.setPosition(args)
+	case "seed":
+		err = s.setSeed(args)
+	case "fen":
+		s.send("fen %s", s.state.FEN())
+	case "legal":
+		s.sendLegal()
+	case "move":
+		err = s.play(args)
+	case "played":
+	case "go":
+		err = s.goSearch(args)
+	default:
+		err = fmt.Errorf("%w %q", ErrUnknownCommand, command)
+	}
+	if err != nil {
+		s.send("error %v", err)
+	}
+	return true
+}
+
+func (s *Server) send(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+func (s *Server) reset() {
+	s.state = engine.NewGame()
+	s.state.RandSeed = s.seed
+	s.applyRules(s.state)
+}
+
+func (s *Server) applyRules(state *engine.GameState) {
+	rules := s.rules
+	state.Rules = &rules
+}
+
+func (s *Server) setOption(args []string) error {
+	// setoption name <id> value <x>
+	if len(args) != 4 || args[0] != "name" || args[2] != "value" {
+		return errors.New("expected setoption name <id> value <x>")
+	}
+	if !strings.EqualFold(args[1], "Rules") {
+		return fmt.Errorf("unknown option %q", args[1])
+	}
+	rules, err := engine.RulesPreset(strings.ToLower(args[3]))
+	if err != nil {
+		return err
+	}
+	s.rules = rules
+	// the table does not tell positions under different rules apart
+	s.table.Clear()
+	return nil
+}
+
+func (s *Server) setPosition(args []string) error {
+	fen, moves, seeded := engine.StartFEN, []string(nil), false
+	if i := slices.Index(args, "moves"); i >= 0 {
+		args, moves = args[:i], args[i+1:]
+	}
+	switch {
+	case len(args) == 1 && args[0] == "startpos":
+	case len(args) > 1 && args[0] == "fen":
+		fen = strings.Join(args[1:], " ")
+		// a SwapFEN carries a seed as its eighth field
+		seeded = len(args[1:]) >= 8
+	default:
+		return errors.New("expected position startpos|fen <SwapFEN> [moves <move>...]")
+	}
+
+	state, err := engine.ParseFEN(fen)
+	if err != nil {
+		return err
+	}
+	if !seeded {
+		state.RandSeed = s.seed
+	}
+	s.applyRules(state)
+	for _, raw := range moves {
+		move, err := ParseMove(raw)
+		if err == nil {
+			err = engine.ApplyMove(state, move)
+		}
+		if err != nil {
+			return fmt.Errorf("move %s: %w", raw, err)
+		}
+	}
+	s.state = state
+	return nil
+}
+
+func (s *Server) setSeed(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected seed <n>")
+	}
+	seed, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid seed %q", args[0])
+	}
+	s.seed = seed
+	s.state.RandSeed = seed
+	return nil
+}
+
+func (s *Server) sendLegal() {
+	var b strings.Builder
+	b.WriteString("legal")
+	if engine.GameOutcome(s.state) == engine.OutcomeNone {
+		for _, move := range engine.LegalMoves(s.state) {
+			b.WriteString(" " + FormatMove(move))
+		}
+	}
+	s.send("%s", b.String())
+}
+
+func (s *Server) play(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected move <move>")
+	}
+	if outcome := engine.GameOutcome(s.state); outcome != engine.OutcomeNone {
+		return fmt.Errorf("the game is over: %s", outcome)
+	}
+	move, err := ParseMove(args[0])
+	if err != nil {
+		return err
+	}
+	res, err := engine.ApplyMoveResult(s.state, move)
+	if err != nil {
+		return fmt.Errorf("move %s: %w", args[0], err)
+	}
+	s.send("played %s", FormatResult(res))
+	s.send("fen %s", s.state.FEN())
+	if outcome := engine.GameOutcome(s.state); outcome != engine.OutcomeNone {
+		s.send("result %s %s", ResultString(s.state, outcome), outcome)
+	}
+	return nil
+}
+
+// goSearch starts a search of the current position. Its replies come from
+// the search goroutine, so stop and isready are answered meanwhile.
+func (s *Server) goSearch(args []string) error {
+	limits, infinite, err := s.parseGo(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	limits.Context = ctx
+	srch := &search{cancel: cancel, done: make(chan struct{})}
+	s.search = srch
+	state := s.state.Clone()
+	go func() {
+		defer close(srch.done)
+		defer cancel()
+		if engine.GameOutcome(state) != engine.OutcomeNone {
+			s.send("bestmove 0000")
+			return
+		}
+		started := time.Now()
+		res, err := bot.Expectimax{Table: s.table}.SearchWithLimits(state, limits)
+		if err == nil {
+			s.send("info depth %d score cp %d nodes %d time %d", res.Depth, int(res.Score*100), res.Nodes, time.Since(started).Milliseconds())
+		}
+		if infinite {
+			// infinite searches name their move only once stopped
+			<-ctx.Done()
+		}
+		if err != nil {
+			s.send("bestmove 0000")
+			return
+		}
+		s.send("bestmove %s", FormatMove(res.Move))
+	}()
+	return nil
+}
+
+// parseGo reads the limits of a go command. Without any it searches
+// bot.DefaultDepth plies; the clock limits give each move an even share of
+// the remaining time.
+func (s *Server) parseGo(args []string) (limits bot.SearchLimits, infinite bool, err error) {
+	var clock, inc [2]time.Duration
+	movesToGo := 30
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" {
+			infinite = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return limits, false, fmt.Errorf("go: missing value of %s", args[i])
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n < 0 {
+			return limits, false, fmt.Errorf("go: invalid %s %q", args[i], args[i+1])
+		}
+		ms := time.Duration(n) * time.Millisecond
+		switch args[i] {
+		case "depth":
+			limits.Depth = n
+		case "nodes":
+			limits.Nodes = n
+		case "movetime":
+			limits.MoveTime = ms
+		case "wtime":
+			clock[engine.White] = ms
+		case "btime":
+			clock[engine.Black] = ms
+		case "winc":
+			inc[engine.White] = ms
+		case "binc":
+			inc[engine.Black] = ms
+		case "movestogo":
+			movesToGo = max(n, 1)
+		default:
+			return limits, false, fmt.Errorf("go: unknown limit %q", args[i])
+		}
+		i++
+	}
+
+	turn := s.state.Turn
+	if limits.MoveTime == 0 && clock[turn] > 0 {
+		limits.MoveTime = clock[turn]/time.Duration(movesToGo) + inc[turn]/2
+	}
+	if limits.Depth == 0 && limits.Nodes == 0 && limits.MoveTime == 0 && !infinite {
+		limits.Depth = bot.DefaultDepth
+	}
+	return limits, infinite, nil
+}
+
+// stop ends the search in progress, if any, once it has replied.
+func (s *Server) stop() {
+	if s.search != nil {
+		s.search.cancel()
+	}
+	s.wait()
+}
+
+// wait blocks until the search in progress, if any, has replied.
+func (s *Server) wait() {
+	if s.search != nil {
+		<-s.search.done
+		s.search = nil
+	}
+}
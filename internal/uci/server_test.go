@@ -0,0 +1,214 @@
+package uci
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/divijg19/Swapchess/engine"
+)
+
+// session drives a Server through pipes, like a GUI driving the engine
+// process.
+type session struct {
+	t       *testing.T
+	in      *io.PipeWriter
+	replies *bufio.Scanner
+	done    chan error
+}
+
+func startSession(t *testing.T) *session {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	s := &session{t: t, in: inW, replies: bufio.NewScanner(outR), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(outW).Serve(inR)
+		outW.Close()
+		s.done <- err
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		// drain replies so the server is never blocked writing
+		go io.Copy(io.Discard, outR)
+		<-s.done
+	})
+	return s
+}
+
+func (s *session) send(line string) {
+	s.t.Helper()
+	if _, err := io.WriteString(s.in, line+"\n"); err != nil {
+		s.t.Fatalf("cannot send %q: %v", line, err)
+	}
+}
+
+// expect reads the next reply and checks that it starts with prefix.
+func (s *session) expect(prefix string) string {
+	s.t.Helper()
+	if !s.replies.Scan() {
+		s.t.Fatalf("expected a reply starting with %q, got the end of output", prefix)
+	}
+	line := s.replies.Text()
+	if !strings.HasPrefix(line, prefix) {
+		s.t.Fatalf("expected a reply starting with %q, got %q", prefix, line)
+	}
+	return line
+}
+
+func TestServerHandshake(t *testing.T) {
+	s := startSession(t)
+	s.send("uci")
+	s.expect("id name Swapchess")
+	s.expect("option name Rules type combo default classic var classic")
+	s.expect("uciok")
+	s.send("isready")
+	s.expect("readyok")
+}
+
+func TestServerReportsPositionsAndSwaps(t *testing.T) {
+	s := startSession(t)
+	s.send("position startpos moves e2e4 e7e5")
+	s.send("fen")
+	after := s.expect("fen ")
+
+	// the same moves from the same seed give the same swaps
+	state, err := engine.ParseFEN(engine.StartFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range []string{"e2e4", "e7e5"} {
+		move, _ := ParseMove(raw)
+		if err := engine.ApplyMove(state, move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "fen " + state.FEN(); after != want {
+		t.Fatalf("expected %q, got %q", want, after)
+	}
+
+	s.send("legal")
+	legal := strings.Fields(s.expect("legal "))[1:]
+	if len(legal) != len(engine.LegalMoves(state)) {
+		t.Fatalf("expected %d legal moves, got %v", len(engine.LegalMoves(state)), legal)
+	}
+
+	s.send("seed 9")
+	s.send("move " + legal[0])
+	move, _ := ParseMove(legal[0])
+	state.RandSeed = 9
+	res, err := engine.ApplyMoveResult(state, move)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.expect("played "), "played "+FormatResult(res); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if got, want := s.expect("fen "), "fen "+state.FEN(); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if !strings.HasSuffix(state.FEN(), " 9") {
+		t.Fatalf("expected the seed to be kept, got %q", state.FEN())
+	}
+}
+
+func TestServerKeepsTheSeedForLaterPositions(t *testing.T) {
+	s := startSession(t)
+	s.send("seed 42")
+	s.send("position startpos moves e2e4")
+	s.send("fen")
+
+	state := engine.NewGame()
+	state.RandSeed = 42
+	move, _ := ParseMove("e2e4")
+	if err := engine.ApplyMove(state, move); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.expect("fen "), "fen "+state.FEN(); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	// a SwapFEN brings its own seed
+	s.send("position fen " + engine.StartFEN)
+	s.send("fen")
+	if got, want := s.expect("fen "), "fen "+engine.StartFEN; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	s.send("position fen rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	s.send("fen")
+	if got := s.expect("fen "); !strings.HasSuffix(got, " 42") {
+		t.Fatalf("expected a plain FEN to take seed 42, got %q", got)
+	}
+}
+
+func TestServerReportsErrorsAndKeepsThePosition(t *testing.T) {
+	s := startSession(t)
+	s.send("position startpos moves e2e4")
+	s.send("fen")
+	before := s.expect("fen ")
+
+	for _, command := range []string{
+		"move e2e5",
+		"move z9",
+		"position startpos moves e2e4 e2e4",
+		"position fen nonsense",
+		"seed x",
+		"setoption name Rules value wild",
+		"go depth x",
+		"castle",
+	} {
+		s.send(command)
+		s.expect("error ")
+	}
+	s.send("fen")
+	if after := s.expect("fen "); after != before {
+		t.Fatalf("expected failed commands to keep %q, got %q", before, after)
+	}
+}
+
+func TestServerReportsTheResult(t *testing.T) {
+	s := startSession(t)
+	s.send("setoption name Rules value safe")
+	s.send("position fen 6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1")
+	s.send("move a1a8")
+	s.expect("played a1a8 noswap check")
+	s.expect("fen ")
+	s.expect("result 1-0 checkmate")
+	s.send("legal")
+	if got := s.expect("legal"); got != "legal" {
+		t.Fatalf("expected no legal moves after mate, got %q", got)
+	}
+	s.send("go depth 1")
+	s.expect("bestmove 0000")
+	s.send("move g8h8")
+	s.expect("error the game is over")
+}
+
+func TestServerSearches(t *testing.T) {
+	s := startSession(t)
+	s.send("position fen 6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1")
+	s.send("go depth 2")
+	s.expect("info depth 2 score cp ")
+	s.expect("bestmove a1a8")
+
+	// an infinite search names its move only when stopped, answering isready
+	// meanwhile; it ends on its own once it has found the mate
+	s.send("go infinite")
+	s.expect("info depth ")
+	s.send("isready")
+	s.expect("readyok")
+	s.send("stop")
+	s.expect("bestmove a1a8")
+}
+
+func TestServerWaitsForTheSearch(t *testing.T) {
+	s := startSession(t)
+	s.send("go nodes 500")
+	s.send("fen")
+	s.expect("info depth ")
+	s.expect("bestmove ")
+	if got := s.expect("fen "); got != "fen "+engine.StartFEN {
+		t.Fatalf("expected the search to leave the position alone, got %q", got)
+	}
+}
@@ -0,0 +1,33 @@
+// Package ucitest provides stub engines for tests of code that drives an
+// engine process over the uci package's protocol.
+package ucitest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// StubEngine writes a shell script engine that completes the handshake, logs
+// every command it gets and runs the shell commands onGo for each go command.
+// It returns the command that starts the engine and the path of its log. The
+// test is skipped where shell scripts cannot run.
+func StubEngine(t testing.TB, onGo string) (command, log string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub engines are shell scripts")
+	}
+	dir := t.TempDir()
+	command, log = filepath.Join(dir, "engine.sh"), filepath.Join(dir, "engine.log")
+	script := "#!/bin/sh\nwhile read -r line; do\n\techo \"$line\" >> '" + log + "'\n\tcase \"$line\" in\n" +
+		"\tuci) echo 'id name stub'; echo uciok ;;\n" +
+		"\tisready) echo readyok ;;\n" +
+		"\tgo|go\\ *) " + onGo + " ;;\n" +
+		"\tquit) exit 0 ;;\n" +
+		"\tesac\ndone\n"
+	if err := os.WriteFile(command, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return command, log
+}
@@ -1,20 +1,30 @@
 package cli
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/divijg19/Swapchess/internal/app"
+)
 
 var ErrTerminalRequired = errors.New("CLI mode requires a real terminal")
 
 type terminalOpener func() (Terminal, error)
 
-func Run(debugRenderer string) error {
-	return run(debugRenderer, openTerminal)
+func Run(opts app.Options) error {
+	return run(opts, openTerminal)
 }
 
-func run(debugRenderer string, open terminalOpener) error {
+func run(opts app.Options, open terminalOpener) error {
+	session, err := app.NewSessionWithOptions(opts)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
 	terminal, err := open()
 	if err != nil {
 		return err
 	}
 
-	return newController(terminal, debugRenderer).Run()
+	return newSessionController(terminal, session).Run()
 }
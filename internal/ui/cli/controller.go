@@ -17,9 +17,13 @@ type controller struct {
 }
 
 func newController(terminal Terminal, debugRenderer string) *controller {
+	return newSessionController(terminal, app.NewSession(debugRenderer))
+}
+
+func newSessionController(terminal Terminal, session *app.Session) *controller {
 	return &controller{
 		terminal: terminal,
-		session:  app.NewSession(debugRenderer),
+		session:  session,
 		renderer: newRenderer(pieces.NewCatalog(filepath.Join("assets", "pieces"))),
 	}
 }
@@ -200,10 +200,24 @@ func TestControllerCtrlCQuitsImmediately(t *testing.T) {
 }
 
 func TestRunReturnsTerminalRequirementError(t *testing.T) {
-	err := run("", func() (Terminal, error) {
+	err := run(app.Options{}, func() (Terminal, error) {
 		return nil, ErrTerminalRequired
 	})
 	if !errors.Is(err, ErrTerminalRequired) {
 		t.Fatalf("expected terminal requirement error, got %v", err)
 	}
 }
+
+func TestRunRejectsInvalidFENBeforeOpeningTerminal(t *testing.T) {
+	opened := false
+	err := run(app.Options{FEN: "not a fen"}, func() (Terminal, error) {
+		opened = true
+		return &fakeTerminal{}, nil
+	})
+	if !errors.Is(err, engine.ErrInvalidFEN) {
+		t.Fatalf("expected invalid FEN error, got %v", err)
+	}
+	if opened {
+		t.Fatalf("expected terminal to stay closed when the FEN is invalid")
+	}
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/cursor"
@@ -54,15 +55,29 @@ type model struct {
 	focus         focusZone
 	helpExpanded  bool
 	moveLogScroll int
+	quitting      bool
 }
 
-func Run(debugRenderer string) error {
-	program := tea.NewProgram(initialModel(debugRenderer), tea.WithAltScreen())
+// botMoveMsg carries the result of a bot search run in the background.
+type botMoveMsg app.BotMove
+
+func Run(opts app.Options) error {
+	// bots search in the background so the board stays responsive
+	opts.AsyncBots = true
+	session, err := app.NewSessionWithOptions(opts)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	program := tea.NewProgram(sessionModel(session), tea.WithAltScreen())
 	return program.Start()
 }
 
 func initialModel(debugRenderer string) model {
-	session := app.NewSession(debugRenderer)
+	return sessionModel(app.NewSession(debugRenderer))
+}
+
+func sessionModel(session *app.Session) model {
 	input := textinput.New()
 	input.Prompt = ""
 	input.Placeholder = session.PromptPlaceholder()
@@ -80,10 +95,43 @@ func initialModel(debugRenderer string) model {
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return m.botCmd()
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(botMoveMsg); ok {
+		m.session.FinishBotSearch(app.BotMove(msg))
+		m.moveLogScroll = 0
+		m.normalizeMoveLogScroll()
+		m.syncInput()
+		return m, m.botCmd()
+	}
+
+	next, cmd := m.update(msg)
+	if updated, ok := next.(model); ok && !updated.quitting {
+		cmd = tea.Batch(cmd, updated.botCmd())
+	}
+	return next, cmd
+}
+
+// botCmd starts the next bot search, if any, as a command whose result
+// comes back as a botMoveMsg.
+func (m model) botCmd() tea.Cmd {
+	search := m.session.StartBotSearch()
+	if search == nil {
+		return nil
+	}
+	return func() tea.Msg { return botMoveMsg(search.Run()) }
+}
+
+// quit cancels any bot search and ends the program.
+func (m model) quit() (tea.Model, tea.Cmd) {
+	m.session.CancelBotSearch()
+	m.quitting = true
+	return m, tea.Quit
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.session.Resize(msg.Width, msg.Height)
@@ -92,12 +140,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case tea.KeyMsg:
 		if strings.EqualFold(msg.String(), "ctrl+c") {
-			return m, tea.Quit
+			return m.quit()
 		}
 
 		switch msg.Type {
 		case tea.KeyCtrlC:
-			return m, tea.Quit
+			return m.quit()
 		case tea.KeyTab:
 			m.toggleFocus()
 			m.normalizeMoveLogScroll()
@@ -123,7 +171,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.focus == focusBoard {
 				result := m.session.ActivateCursor()
 				if result.Quit {
-					return m, tea.Quit
+					return m.quit()
 				}
 				if result.InputMode == app.InputModePromotion {
 					m.focus = focusPrompt
@@ -137,7 +185,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			result := m.session.Submit(m.input.Value())
 			m.input.SetValue("")
 			if result.Quit {
-				return m, tea.Quit
+				return m.quit()
 			}
 			if result.InputMode == app.InputModePromotion {
 				m.focus = focusPrompt
@@ -307,6 +355,7 @@ func (m model) gameFields() []infoField {
 		{Label: "Select", Value: m.selectedLabel()},
 		{Label: "Last", Value: m.session.LastMoveNotation()},
 		{Label: "Castle", Value: castlingLabel(m.session.View.CastlingRights)},
+		{Label: "Seed", Value: strconv.FormatInt(m.session.View.Seed, 10)},
 	}
 	if m.session.DebugRendererEnabled {
 		fields = append(fields, infoField{Label: "Render", Value: string(m.session.Renderer)})
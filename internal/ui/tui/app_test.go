@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/divijg19/Swapchess/engine"
+	"github.com/divijg19/Swapchess/engine/bot"
 	"github.com/divijg19/Swapchess/internal/app"
 )
 
@@ -110,6 +111,57 @@ func TestCtrlCQuits(t *testing.T) {
 	}
 }
 
+func asyncBotModel(t *testing.T) model {
+	t.Helper()
+	session, err := app.NewSessionWithOptions(app.Options{Black: app.PlayerBot, AsyncBots: true, Bot: bot.Expectimax{Depth: 1}})
+	if err != nil {
+		t.Fatalf("NewSessionWithOptions returned error: %v", err)
+	}
+	current := sessionModel(session)
+	current.focus = focusPrompt
+	current.input.SetValue("e2e4")
+	return current
+}
+
+func TestBotRepliesInTheBackground(t *testing.T) {
+	next, cmd := asyncBotModel(t).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	current := next.(model)
+	if len(current.session.MoveLog) != 1 || !current.session.BotThinking() || cmd == nil {
+		t.Fatalf("expected the bot to start thinking after e2e4")
+	}
+
+	next, _ = current.Update(cmd())
+	current = next.(model)
+	if len(current.session.MoveLog) != 2 || current.session.BotThinking() {
+		t.Fatalf("expected the bot reply to be played, got %d moves", len(current.session.MoveLog))
+	}
+}
+
+func TestQuitCancelsBotSearch(t *testing.T) {
+	next, _ := asyncBotModel(t).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, cmd := next.(model).Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	current := next.(model)
+	if cmd == nil || current.session.BotThinking() {
+		t.Fatalf("expected ctrl+c to cancel the search and quit")
+	}
+}
+
+func TestUndoDropsBotSearch(t *testing.T) {
+	next, search := asyncBotModel(t).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	current := next.(model)
+	current.focus = focusBoard
+	next, _ = current.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	current = next.(model)
+	if len(current.session.MoveLog) != 0 || current.session.BotThinking() {
+		t.Fatalf("expected undo to take back e2e4 and cancel the search")
+	}
+
+	next, _ = current.Update(search())
+	if got := len(next.(model).session.MoveLog); got != 0 {
+		t.Fatalf("expected the cancelled search to be ignored, got %d moves", got)
+	}
+}
+
 func TestTabTogglesFocus(t *testing.T) {
 	current := fullSizedModel("")
 
@@ -233,6 +285,9 @@ func TestGameLinesDropSwapSuppressedRow(t *testing.T) {
 	if !strings.Contains(lines, "Cursor") || !strings.Contains(lines, "Select") {
 		t.Fatalf("expected aligned cursor and selected rows, got %q", lines)
 	}
+	if !strings.Contains(lines, "Seed") {
+		t.Fatalf("expected seed row in game state, got %q", lines)
+	}
 }
 
 func TestMoveLogGroupsFullMoves(t *testing.T) {
@@ -271,7 +271,7 @@ func defaultLayoutContent() layoutContent {
 			alignedDualRow("Turn", "white", "Status", "in play", 6),
 			alignedDualRow("Cursor", "e2", "Select", "-", 6),
 			alignedDualRow("Last", "-", "Castle", "W:KQ B:KQ", 6),
-			alignedRow("Render", "engine", 6),
+			alignedDualRow("Seed", "1234567890", "Render", "engine", 6),
 		},
 		LogLines: []string{
 			"01. e2e4  e7e5",
@@ -9,6 +9,12 @@ const (
 	StatusCheck     GameStatus = "check"
 	StatusCheckmate GameStatus = "checkmate"
 	StatusStalemate GameStatus = "stalemate"
+
+	StatusKingCaptured GameStatus = "king_captured"
+
+	StatusDrawFiftyMove            GameStatus = "draw_fifty_move"
+	StatusDrawRepetition           GameStatus = "draw_repetition"
+	StatusDrawInsufficientMaterial GameStatus = "draw_insufficient_material"
 )
 
 func (s GameStatus) String() string {
@@ -19,11 +25,29 @@ func (s GameStatus) String() string {
 		return "checkmate"
 	case StatusStalemate:
 		return "stalemate"
+	case StatusKingCaptured:
+		return "king captured"
+	case StatusDrawFiftyMove:
+		return "draw (50 moves)"
+	case StatusDrawRepetition:
+		return "draw (repetition)"
+	case StatusDrawInsufficientMaterial:
+		return "draw (material)"
 	default:
 		return "in play"
 	}
 }
 
+// IsGameOver reports whether the status ends the game.
+func (s GameStatus) IsGameOver() bool {
+	switch s {
+	case StatusInPlay, StatusCheck, "":
+		return false
+	default:
+		return true
+	}
+}
+
 type CastlingRights struct {
 	WhiteKingSide  bool
 	WhiteQueenSide bool
@@ -52,7 +76,9 @@ type ViewState struct {
 	SuppressNextSwap bool
 	HasEnPassant     bool
 	EnPassant        engine.Position
+	EnPassantPawn    engine.Position // pawn an en passant capture removes
 	CastlingRights   CastlingRights
+	Seed             int64
 	LastMove         *engine.Move
 	SwapEvent        *SwapEvent
 }
@@ -84,17 +110,21 @@ func ViewStateFromGameStateWithMeta(s *engine.GameState, meta SnapshotMeta) View
 			BlackKingSide:  s.BlackCanCastleKingSide,
 			BlackQueenSide: s.BlackCanCastleQueenSide,
 		},
+		Seed: s.RandSeed,
+	}
+	if s.HasEnPassant {
+		// the target sits between the pawn's origin and destination:
+		// white pawns pass ranks 2-3, black pawns ranks 6-7
+		pawnRank := s.EnPassant.Rank + 1
+		if s.EnPassant.Rank >= 4 {
+			pawnRank = s.EnPassant.Rank - 1
+		}
+		vs.EnPassantPawn = engine.Position{File: s.EnPassant.File, Rank: pawnRank}
 	}
 
-	switch {
-	case engine.IsCheckmate(s):
-		vs.Status = StatusCheckmate
-	case engine.IsStalemate(s):
-		vs.Status = StatusStalemate
-	case engine.IsInCheck(s, s.Turn):
+	vs.Status = statusFromOutcome(engine.GameOutcome(s))
+	if vs.Status == StatusInPlay && engine.IsInCheck(s, s.Turn) {
 		vs.Status = StatusCheck
-	default:
-		vs.Status = StatusInPlay
 	}
 
 	if meta.LastMove != nil {
@@ -128,3 +158,22 @@ func ViewStateFromGameStateWithMeta(s *engine.GameState, meta SnapshotMeta) View
 
 	return vs
 }
+
+func statusFromOutcome(outcome engine.Outcome) GameStatus {
+	switch outcome {
+	case engine.OutcomeCheckmate:
+		return StatusCheckmate
+	case engine.OutcomeStalemate:
+		return StatusStalemate
+	case engine.OutcomeKingCaptured:
+		return StatusKingCaptured
+	case engine.OutcomeFiftyMoveRule:
+		return StatusDrawFiftyMove
+	case engine.OutcomeThreefoldRepetition:
+		return StatusDrawRepetition
+	case engine.OutcomeInsufficientMaterial:
+		return StatusDrawInsufficientMaterial
+	default:
+		return StatusInPlay
+	}
+}
@@ -36,6 +36,9 @@ func TestViewStateFromGameStateWithMeta(t *testing.T) {
 	if !vs.HasEnPassant || vs.EnPassant != state.EnPassant {
 		t.Fatalf("expected en-passant square to be propagated")
 	}
+	if vs.EnPassantPawn != (engine.Position{File: 4, Rank: 3}) {
+		t.Fatalf("expected en-passant pawn on e4, got %+v", vs.EnPassantPawn)
+	}
 	if vs.CastlingRights.WhiteKingSide {
 		t.Fatalf("expected white king-side castling right to be false")
 	}
@@ -73,3 +76,44 @@ func TestViewStateStatusDetectsCheck(t *testing.T) {
 		t.Fatalf("expected check status, got %s", vs.Status)
 	}
 }
+
+func TestViewStateStatusDetectsDraws(t *testing.T) {
+	bare, err := engine.ParseFEN("4k3/8/8/8/8/8/8/4K3 w - -")
+	if err != nil {
+		t.Fatalf("ParseFEN returned error: %v", err)
+	}
+	vs := ViewStateFromGameState(bare)
+	if vs.Status != StatusDrawInsufficientMaterial || !vs.Status.IsGameOver() {
+		t.Fatalf("expected insufficient-material draw, got %s", vs.Status)
+	}
+
+	fifty, err := engine.ParseFEN("4k3/8/8/8/8/8/8/R3K3 b - - 100 80")
+	if err != nil {
+		t.Fatalf("ParseFEN returned error: %v", err)
+	}
+	vs = ViewStateFromGameState(fifty)
+	if vs.Status != StatusDrawFiftyMove {
+		t.Fatalf("expected fifty-move draw, got %s", vs.Status)
+	}
+	if vs.Status.String() != "draw (50 moves)" {
+		t.Fatalf("unexpected draw label %q", vs.Status.String())
+	}
+
+	if StatusCheck.IsGameOver() || StatusInPlay.IsGameOver() {
+		t.Fatalf("check and in-play must not end the game")
+	}
+}
+
+func TestViewStateStatusReportsKingCapture(t *testing.T) {
+	kingless, err := engine.ParseFEN("8/8/8/8/8/2r5/P7/7k w - -")
+	if err != nil {
+		t.Fatalf("ParseFEN returned error: %v", err)
+	}
+	vs := ViewStateFromGameState(kingless)
+	if vs.Status != StatusKingCaptured || !vs.Status.IsGameOver() {
+		t.Fatalf("expected king-captured status, got %s", vs.Status)
+	}
+	if vs.Status.String() != "king captured" {
+		t.Fatalf("unexpected king-captured label %q", vs.Status.String())
+	}
+}